@@ -0,0 +1,5 @@
+DROP INDEX idx_dice_rolls_campaign_id;
+
+DROP INDEX idx_dice_rolls_character_id;
+
+DROP TABLE dice_rolls;
@@ -0,0 +1,14 @@
+CREATE TABLE dice_rolls (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    character_id INTEGER NOT NULL,
+    campaign_id INTEGER,
+    user_id INTEGER NOT NULL,
+    expression TEXT NOT NULL,
+    total INTEGER NOT NULL,
+    breakdown TEXT NOT NULL,
+    detail TEXT NOT NULL,
+    created TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_dice_rolls_character_id ON dice_rolls(character_id);
+CREATE INDEX idx_dice_rolls_campaign_id ON dice_rolls(campaign_id);
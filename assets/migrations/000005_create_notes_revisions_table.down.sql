@@ -0,0 +1,3 @@
+DROP INDEX idx_notes_revisions_character_id;
+
+DROP TABLE notes_revisions;
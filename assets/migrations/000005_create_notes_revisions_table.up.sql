@@ -0,0 +1,9 @@
+CREATE TABLE notes_revisions (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    character_id INTEGER NOT NULL,
+    user_id INTEGER,
+    notes TEXT NOT NULL,
+    created TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_notes_revisions_character_id ON notes_revisions(character_id);
@@ -0,0 +1,7 @@
+DROP TRIGGER search_characters_delete;
+
+DROP TRIGGER search_characters_update;
+
+DROP TRIGGER search_characters_insert;
+
+DROP TABLE search_characters;
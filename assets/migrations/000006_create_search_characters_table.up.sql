@@ -0,0 +1,16 @@
+CREATE VIRTUAL TABLE search_characters USING fts5(name, notes, tokenize = 'unicode61 remove_diacritics 2');
+
+INSERT INTO search_characters (rowid, name, notes) SELECT id, name, notes FROM character_character;
+
+CREATE TRIGGER search_characters_insert AFTER INSERT ON character_character BEGIN
+    INSERT INTO search_characters (rowid, name, notes) VALUES (new.id, new.name, new.notes);
+END;
+
+CREATE TRIGGER search_characters_update AFTER UPDATE OF name, notes ON character_character BEGIN
+    DELETE FROM search_characters WHERE rowid = old.id;
+    INSERT INTO search_characters (rowid, name, notes) VALUES (new.id, new.name, new.notes);
+END;
+
+CREATE TRIGGER search_characters_delete AFTER DELETE ON character_character BEGIN
+    DELETE FROM search_characters WHERE rowid = old.id;
+END;
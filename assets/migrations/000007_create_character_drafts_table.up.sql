@@ -0,0 +1,5 @@
+CREATE TABLE character_drafts (
+    user_id INTEGER NOT NULL PRIMARY KEY,
+    data TEXT NOT NULL,
+    modified TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+DROP TABLE level_ups;
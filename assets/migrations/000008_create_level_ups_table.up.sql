@@ -0,0 +1,15 @@
+CREATE TABLE level_ups (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    character_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    level INTEGER NOT NULL,
+    capability_id INTEGER,
+    capability_name TEXT NOT NULL,
+    health_method TEXT NOT NULL,
+    hit_die INTEGER NOT NULL,
+    health_gain INTEGER NOT NULL,
+    mana_gain INTEGER NOT NULL,
+    created TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_level_ups_character_id ON level_ups(character_id);
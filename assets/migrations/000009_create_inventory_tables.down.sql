@@ -0,0 +1,3 @@
+DROP TABLE money_transactions;
+DROP TABLE inventory_imports;
+DROP TABLE inventory_items;
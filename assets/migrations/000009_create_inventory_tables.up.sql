@@ -0,0 +1,36 @@
+CREATE TABLE inventory_items (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    character_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    weight REAL NOT NULL,
+    container_id INTEGER,
+    is_container BOOLEAN NOT NULL DEFAULT FALSE,
+    equipped BOOLEAN NOT NULL DEFAULT FALSE,
+    slot TEXT NOT NULL DEFAULT '',
+    defense INTEGER NOT NULL DEFAULT 0,
+    created TIMESTAMP NOT NULL,
+    modified TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_inventory_items_character_id ON inventory_items(character_id);
+
+CREATE TABLE inventory_imports (
+    character_id INTEGER NOT NULL PRIMARY KEY,
+    equipment TEXT NOT NULL,
+    created TIMESTAMP NOT NULL
+);
+
+CREATE TABLE money_transactions (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    character_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    pp INTEGER NOT NULL,
+    po INTEGER NOT NULL,
+    pa INTEGER NOT NULL,
+    pc INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    created TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_money_transactions_character_id ON money_transactions(character_id);
@@ -0,0 +1 @@
+DROP TABLE campaign_invitations;
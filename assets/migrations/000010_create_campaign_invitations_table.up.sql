@@ -0,0 +1,11 @@
+CREATE TABLE campaign_invitations (
+    hashed_token TEXT NOT NULL PRIMARY KEY,
+    campaign_id INTEGER NOT NULL,
+    email TEXT NOT NULL,
+    invited_by_id INTEGER NOT NULL,
+    created TIMESTAMP NOT NULL,
+    expiry TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_campaign_invitations_campaign_id ON campaign_invitations(campaign_id);
+CREATE INDEX idx_campaign_invitations_email ON campaign_invitations(email);
@@ -0,0 +1,2 @@
+DROP TABLE encounter_combatants;
+DROP TABLE encounters;
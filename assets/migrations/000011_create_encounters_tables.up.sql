@@ -0,0 +1,27 @@
+CREATE TABLE encounters (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    campaign_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    round INTEGER NOT NULL DEFAULT 0,
+    current_combatant_id INTEGER,
+    ended TIMESTAMP,
+    created TIMESTAMP NOT NULL,
+    modified TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_encounters_campaign_id ON encounters(campaign_id);
+
+CREATE TABLE encounter_combatants (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    encounter_id INTEGER NOT NULL,
+    character_id INTEGER,
+    name TEXT NOT NULL,
+    initiative_bonus INTEGER NOT NULL DEFAULT 0,
+    initiative INTEGER,
+    defense INTEGER NOT NULL DEFAULT 0,
+    health_max INTEGER NOT NULL DEFAULT 0,
+    health_remaining INTEGER NOT NULL DEFAULT 0,
+    created TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_encounter_combatants_encounter_id ON encounter_combatants(encounter_id);
@@ -0,0 +1,20 @@
+CREATE TABLE character_effects (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    character_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    defense INTEGER NOT NULL DEFAULT 0,
+    initiative INTEGER NOT NULL DEFAULT 0,
+    attack_melee INTEGER NOT NULL DEFAULT 0,
+    attack_ranged INTEGER NOT NULL DEFAULT 0,
+    attack_magic INTEGER NOT NULL DEFAULT 0,
+    duration_unit TEXT NOT NULL,
+    duration INTEGER NOT NULL DEFAULT 0,
+    rounds_remaining INTEGER,
+    created_by_id INTEGER NOT NULL,
+    created TIMESTAMP NOT NULL,
+    ended TIMESTAMP,
+    end_reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_character_effects_character_id ON character_effects(character_id);
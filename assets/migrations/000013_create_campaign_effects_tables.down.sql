@@ -0,0 +1,2 @@
+DROP TABLE campaign_effect_exclusions;
+DROP TABLE campaign_effects;
@@ -0,0 +1,26 @@
+CREATE TABLE campaign_effects (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    campaign_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    defense INTEGER NOT NULL DEFAULT 0,
+    initiative INTEGER NOT NULL DEFAULT 0,
+    attack_melee INTEGER NOT NULL DEFAULT 0,
+    attack_ranged INTEGER NOT NULL DEFAULT 0,
+    attack_magic INTEGER NOT NULL DEFAULT 0,
+    duration_unit TEXT NOT NULL,
+    duration INTEGER NOT NULL DEFAULT 0,
+    rounds_remaining INTEGER,
+    created_by_id INTEGER NOT NULL,
+    created TIMESTAMP NOT NULL,
+    ended TIMESTAMP,
+    end_reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_campaign_effects_campaign_id ON campaign_effects(campaign_id);
+
+CREATE TABLE campaign_effect_exclusions (
+    campaign_effect_id INTEGER NOT NULL,
+    character_id INTEGER NOT NULL,
+    PRIMARY KEY (campaign_effect_id, character_id)
+);
@@ -0,0 +1,3 @@
+DROP TABLE rest_effects;
+DROP TABLE rest_characters;
+DROP TABLE rests;
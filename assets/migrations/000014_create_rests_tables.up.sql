@@ -0,0 +1,31 @@
+CREATE TABLE rests (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    campaign_id INTEGER,
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    created TIMESTAMP NOT NULL,
+    undone TIMESTAMP
+);
+
+CREATE TABLE rest_characters (
+    rest_id INTEGER NOT NULL,
+    character_id INTEGER NOT NULL,
+    health_before INTEGER NOT NULL,
+    health_after INTEGER NOT NULL,
+    mana_before INTEGER NOT NULL,
+    mana_after INTEGER NOT NULL,
+    recovery_before INTEGER NOT NULL,
+    recovery_after INTEGER NOT NULL,
+    rolls TEXT NOT NULL,
+    effects TEXT NOT NULL,
+    PRIMARY KEY (rest_id, character_id)
+);
+
+CREATE TABLE rest_effects (
+    rest_id INTEGER NOT NULL,
+    character_effect_id INTEGER,
+    campaign_effect_id INTEGER,
+    excluded_character_id INTEGER
+);
+
+CREATE INDEX idx_rest_effects_rest_id ON rest_effects(rest_id);
@@ -0,0 +1,3 @@
+ALTER TABLE encounter_combatants DROP COLUMN damage_reduction;
+
+ALTER TABLE inventory_items DROP COLUMN damage;
@@ -0,0 +1,3 @@
+ALTER TABLE inventory_items ADD COLUMN damage TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE encounter_combatants ADD COLUMN damage_reduction INTEGER NOT NULL DEFAULT 0;
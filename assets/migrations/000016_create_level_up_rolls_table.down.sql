@@ -0,0 +1 @@
+DROP TABLE level_up_rolls;
@@ -0,0 +1,7 @@
+CREATE TABLE level_up_rolls (
+    character_id INTEGER NOT NULL,
+    level INTEGER NOT NULL,
+    hit_die INTEGER NOT NULL,
+    created TIMESTAMP NOT NULL,
+    PRIMARY KEY (character_id, level)
+);
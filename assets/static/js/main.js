@@ -0,0 +1,9 @@
+// Let htmx swap validation errors (422) and edit conflicts (409) into the
+// page like regular responses.
+document.addEventListener("htmx:beforeSwap", function (event) {
+    const status = event.detail.xhr.status;
+    if (status === 422 || status === 409) {
+        event.detail.shouldSwap = true;
+        event.detail.isError = false;
+    }
+});
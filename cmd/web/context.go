@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/Crocmagnon/charasheet-go/internal/authz"
 	"github.com/Crocmagnon/charasheet-go/internal/database"
 )
 
@@ -11,6 +12,11 @@ type contextKey string
 
 const (
 	authenticatedUserContextKey = contextKey("authenticatedUser")
+	characterContextKey         = contextKey("character")
+	characterAccessContextKey   = contextKey("characterAccess")
+	campaignContextKey          = contextKey("campaign")
+	campaignAccessContextKey    = contextKey("campaignAccess")
+	encounterContextKey         = contextKey("encounter")
 )
 
 func contextSetAuthenticatedUser(r *http.Request, user *database.User) *http.Request {
@@ -26,3 +32,65 @@ func contextGetAuthenticatedUser(r *http.Request) *database.User {
 
 	return user
 }
+
+func contextSetCharacter(r *http.Request, character *database.Character, access authz.Access) *http.Request {
+	ctx := context.WithValue(r.Context(), characterContextKey, character)
+	ctx = context.WithValue(ctx, characterAccessContextKey, access)
+	return r.WithContext(ctx)
+}
+
+func contextGetCharacter(r *http.Request) *database.Character {
+	character, ok := r.Context().Value(characterContextKey).(*database.Character)
+	if !ok {
+		return nil
+	}
+
+	return character
+}
+
+func contextGetCharacterAccess(r *http.Request) authz.Access {
+	access, ok := r.Context().Value(characterAccessContextKey).(authz.Access)
+	if !ok {
+		return authz.Access{}
+	}
+
+	return access
+}
+
+func contextSetCampaign(r *http.Request, campaign *database.Campaign, access authz.Access) *http.Request {
+	ctx := context.WithValue(r.Context(), campaignContextKey, campaign)
+	ctx = context.WithValue(ctx, campaignAccessContextKey, access)
+	return r.WithContext(ctx)
+}
+
+func contextGetCampaign(r *http.Request) *database.Campaign {
+	campaign, ok := r.Context().Value(campaignContextKey).(*database.Campaign)
+	if !ok {
+		return nil
+	}
+
+	return campaign
+}
+
+func contextGetCampaignAccess(r *http.Request) authz.Access {
+	access, ok := r.Context().Value(campaignAccessContextKey).(authz.Access)
+	if !ok {
+		return authz.Access{}
+	}
+
+	return access
+}
+
+func contextSetEncounter(r *http.Request, encounter *database.Encounter) *http.Request {
+	ctx := context.WithValue(r.Context(), encounterContextKey, encounter)
+	return r.WithContext(ctx)
+}
+
+func contextGetEncounter(r *http.Request) *database.Encounter {
+	encounter, ok := r.Context().Value(encounterContextKey).(*database.Encounter)
+	if !ok {
+		return nil
+	}
+
+	return encounter
+}
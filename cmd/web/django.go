@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/django"
+)
+
+func (app *application) getDjangoSession(r *http.Request) (*database.DjangoSession, error) {
+	sessionIDCookie, err := r.Cookie(django.SessionCookieName)
+
+	switch {
+	case errors.Is(err, http.ErrNoCookie):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("getting cookie %q: %w", django.SessionCookieName, err)
+	}
+
+	session, err := app.db.GetSession(sessionIDCookie.Value)
+	if err != nil {
+		return nil, fmt.Errorf("getting session from db: %w", err)
+	}
+
+	return session, nil
+}
+
+func (app *application) getUserFromDjangoSession(r *http.Request) (*database.User, error) {
+	if app.djangoSessionSigner == nil {
+		return nil, nil
+	}
+
+	session, err := app.getDjangoSession(r)
+	if err != nil || session == nil {
+		return nil, err
+	}
+
+	sessionData, err := session.Decode(app.djangoSessionSigner)
+	if err != nil {
+		app.logger.Warn("ignoring invalid django session", "error", err)
+		return nil, nil
+	}
+
+	userID, err := strconv.Atoi(sessionData.AuthUserID)
+	if err != nil {
+		return nil, nil
+	}
+
+	user, err := app.db.GetUser(userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	hashMatches, err := django.VerifySessionAuthHash(sessionData.AuthUserHash, user.HashedPassword, app.djangoSessionSigner.Keys())
+	if err != nil {
+		return nil, fmt.Errorf("verifying session auth hash: %w", err)
+	}
+
+	if !hashMatches {
+		return nil, nil
+	}
+
+	return user, nil
+}
+
+// loginDjangoSession mirrors django.contrib.auth.login: the current Django
+// session, if any, is replaced by a new one under a fresh key so that the
+// user is also logged into the Django app.
+func (app *application) loginDjangoSession(w http.ResponseWriter, r *http.Request, user *database.User) error {
+	if app.djangoSessionSigner == nil {
+		return nil
+	}
+
+	var sessionData database.DjangoSessionData
+
+	oldSession, err := app.getDjangoSession(r)
+	if err != nil {
+		return err
+	}
+
+	if oldSession != nil {
+		oldSessionData, err := oldSession.Decode(app.djangoSessionSigner)
+		if err == nil && (oldSessionData.AuthUserID == "" || oldSessionData.AuthUserID == strconv.Itoa(user.ID)) {
+			sessionData = *oldSessionData
+		}
+
+		err = app.db.DeleteSession(oldSession.SessionKey)
+		if err != nil {
+			return fmt.Errorf("deleting previous session: %w", err)
+		}
+	}
+
+	authHash, err := django.SessionAuthHash(user.HashedPassword, app.config.django.secretKey)
+	if err != nil {
+		return err
+	}
+
+	sessionData.AuthUserID = strconv.Itoa(user.ID)
+	sessionData.AuthUserBackend = django.ModelBackend
+	sessionData.AuthUserHash = authHash
+
+	encoded, err := app.djangoSessionSigner.SignObject(sessionData)
+	if err != nil {
+		return fmt.Errorf("encoding session: %w", err)
+	}
+
+	sessionKey, err := django.NewSessionKey()
+	if err != nil {
+		return err
+	}
+
+	expiry := time.Now().Add(django.SessionCookieAge)
+
+	err = app.db.InsertSession(sessionKey, encoded, expiry)
+	if err != nil {
+		return fmt.Errorf("inserting session: %w", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     django.SessionCookieName,
+		Value:    sessionKey,
+		Path:     "/",
+		Expires:  expiry,
+		MaxAge:   int(django.SessionCookieAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   true,
+	})
+
+	return nil
+}
+
+func (app *application) logoutDjangoSession(w http.ResponseWriter, r *http.Request) error {
+	session, err := app.getDjangoSession(r)
+	if err != nil {
+		return err
+	}
+
+	if session != nil {
+		err = app.db.DeleteSession(session.SessionKey)
+		if err != nil {
+			return fmt.Errorf("deleting session: %w", err)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     django.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   true,
+	})
+
+	return nil
+}
+
+// deleteDjangoSessions removes every Django session belonging to userID.
+// Session data is signed, so rows have to be decoded one by one.
+func (app *application) deleteDjangoSessions(userID int) error {
+	if app.djangoSessionSigner == nil {
+		return nil
+	}
+
+	sessions, err := app.db.GetSessions()
+	if err != nil {
+		return fmt.Errorf("getting sessions from db: %w", err)
+	}
+
+	for _, session := range sessions {
+		sessionData, err := session.Decode(app.djangoSessionSigner)
+		if err != nil || sessionData.AuthUserID != strconv.Itoa(userID) {
+			continue
+		}
+
+		err = app.db.DeleteSession(session.SessionKey)
+		if err != nil {
+			return fmt.Errorf("deleting session: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"log/slog"
 	"net/http"
 	"runtime/debug"
+
+	"github.com/Crocmagnon/charasheet-go/internal/response"
 )
 
 func (app *application) reportServerError(r *http.Request, err error) {
@@ -39,9 +41,26 @@ func (app *application) serverError(w http.ResponseWriter, r *http.Request, err
 	http.Error(w, message, http.StatusInternalServerError)
 }
 
+func (app *application) errorPage(w http.ResponseWriter, r *http.Request, status int, message string) {
+	data := app.newTemplateData(r)
+	data["Status"] = status
+	data["StatusText"] = http.StatusText(status)
+	data["Message"] = message
+
+	err := response.Page(w, status, data, "pages/error.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
 func (app *application) notFound(w http.ResponseWriter, r *http.Request) {
 	message := "The requested resource could not be found"
-	http.Error(w, message, http.StatusNotFound)
+	app.errorPage(w, r, http.StatusNotFound, message)
+}
+
+func (app *application) forbidden(w http.ResponseWriter, r *http.Request) {
+	message := "You are not allowed to access this resource"
+	app.errorPage(w, r, http.StatusForbidden, message)
 }
 
 func (app *application) badRequest(w http.ResponseWriter, r *http.Request, err error) {
@@ -1,20 +1,31 @@
 package main
 
 import (
-	"html/template"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
-
+	"unicode/utf8"
+
+	"github.com/Crocmagnon/charasheet-go/internal/authz"
+	"github.com/Crocmagnon/charasheet-go/internal/catalog"
+	"github.com/Crocmagnon/charasheet-go/internal/charjson"
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+	"github.com/Crocmagnon/charasheet-go/internal/diff"
 	"github.com/Crocmagnon/charasheet-go/internal/password"
 	"github.com/Crocmagnon/charasheet-go/internal/request"
 	"github.com/Crocmagnon/charasheet-go/internal/response"
+	"github.com/Crocmagnon/charasheet-go/internal/rules"
+	"github.com/Crocmagnon/charasheet-go/internal/sheetpdf"
 	"github.com/Crocmagnon/charasheet-go/internal/token"
 	"github.com/Crocmagnon/charasheet-go/internal/validator"
 	"github.com/Crocmagnon/charasheet-go/internal/version"
-	"github.com/gomarkdown/markdown"
-	"github.com/gomarkdown/markdown/html"
-	"github.com/gomarkdown/markdown/parser"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -112,6 +123,12 @@ func (app *application) signup(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		err = app.loginDjangoSession(w, r, &database.User{ID: id, Email: form.Email, HashedPassword: hashedPassword})
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 	}
 }
@@ -171,6 +188,22 @@ func (app *application) login(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if password.NeedsRehash(user.HashedPassword) {
+			hashedPassword, err := password.Hash(form.Password)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			err = app.db.UpdateUserHashedPassword(user.ID, hashedPassword)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			user.HashedPassword = hashedPassword
+		}
+
 		session, err := app.sessionStore.Get(r, "session")
 		if err != nil {
 			app.serverError(w, r, err)
@@ -192,6 +225,12 @@ func (app *application) login(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		err = app.loginDjangoSession(w, r, user)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
 		http.Redirect(w, r, redirectPath, http.StatusSeeOther)
 	}
 }
@@ -211,6 +250,12 @@ func (app *application) logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = app.logoutDjangoSession(w, r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
@@ -374,6 +419,12 @@ func (app *application) passwordReset(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		err = app.deleteDjangoSessions(passwordReset.UserID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
 		http.Redirect(w, r, "/password-reset-confirmation", http.StatusSeeOther)
 	}
 }
@@ -387,21 +438,125 @@ func (app *application) passwordResetConfirmation(w http.ResponseWriter, r *http
 	}
 }
 
-func (app *application) characterNotesChange(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+
+	results, err := app.searchCharacters(contextGetAuthenticatedUser(r), query)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Query"] = query
+	data["Results"] = results
+
+	if r.Header.Get("HX-Request") == "true" {
+		err = response.Partial(w, http.StatusOK, data, nil, "partials/search_results.tmpl", "partial:search_results")
+	} else {
+		err = response.Page(w, http.StatusOK, data, "pages/search.tmpl")
+	}
+
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterDetail(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["CharacterAccess"] = contextGetCharacterAccess(r)
+
+	if contextGetCharacterAccess(r).Can(authz.ActionViewNotes) {
+		htmlNotes, err := app.renderNotes(r, character, character.Notes)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		data["HTMLNotes"] = htmlNotes
+	}
+
+	data["Counters"] = characterCounters(character)
+	data["RestPath"] = fmt.Sprintf("/character/%d/rest", character.ID)
+
+	history, err := app.diceRollHistory(character)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data["RollHistory"] = history
+
+	capabilities, err := app.db.GetCharacterCapabilities(character.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data["Capabilities"] = capabilities
+
+	inventory, err := app.characterInventory(character)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data["Inventory"] = inventory
+
+	if contextGetCharacterAccess(r).Can(authz.ActionRollDice) {
+		attack, err := app.attackView(character)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		data["Attack"] = attack
+	}
+
+	transactions, err := app.db.GetMoneyTransactions(character.ID, moneyTransactionsLength)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	character, err := app.db.GetCharacter(id)
+	data["MoneyTransactions"] = transactions
+
+	effectHistory, err := app.db.GetEffectHistory(character.ID, effectHistoryLength)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
+	data["Effects"] = effectViews(character.Effects)
+	data["EffectHistory"] = effectViews(effectHistory)
+
+	err = response.Page(w, http.StatusOK, data, "pages/character.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterStats(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	err := response.JSON(w, http.StatusOK, rules.Compute(character))
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterNotesChange(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
 	var form struct {
-		Notes string `form:"Notes"`
+		Notes       string            `form:"Notes"`
+		BaseNotes   string            `form:"BaseNotes"`
+		TheirNotes  string            `form:"TheirNotes"`
+		Revision    string            `form:"Revision"`
+		Resolutions []diff.Resolution `form:"Resolutions"`
 	}
 
 	switch r.Method {
@@ -409,7 +564,10 @@ func (app *application) characterNotesChange(w http.ResponseWriter, r *http.Requ
 		data := app.newTemplateData(r)
 		data["Character"] = character
 
-		err := response.Partial(w, http.StatusOK, data, nil, "partials/notes_update.tmpl", "partial:notes_update")
+		headers := make(http.Header)
+		headers.Set("ETag", `"`+character.NotesHash()+`"`)
+
+		err := response.Partial(w, http.StatusOK, data, headers, "partials/notes_update.tmpl", "partial:notes_update")
 		if err != nil {
 			app.serverError(w, r, err)
 		}
@@ -420,32 +578,2380 @@ func (app *application) characterNotesChange(w http.ResponseWriter, r *http.Requ
 			return
 		}
 
-		err = app.db.SetCharacterNotes(character.ID, form.Notes)
+		// Django stores "\r\n" line endings, which the browser drops from
+		// the hidden inputs but not from the textarea.
+		base, notes, theirs := diff.NormalizeNewlines(form.BaseNotes), diff.NormalizeNewlines(form.Notes), diff.NormalizeNewlines(form.TheirNotes)
+
+		if len(form.Resolutions) > 0 {
+			if database.NotesHash(theirs) != form.Revision {
+				app.badRequest(w, r, errors.New("notes revision does not match"))
+				return
+			}
+
+			notes = diff.Merge3(base, notes, theirs).Resolve(form.Resolutions)
+			base = theirs
+		} else if database.NotesHash(base) != form.Revision {
+			app.badRequest(w, r, errors.New("notes revision does not match"))
+			return
+		}
+
+		saved, conflict, err := app.saveCharacterNotes(contextGetAuthenticatedUser(r), character.ID, base, notes)
 		if err != nil {
 			app.serverError(w, r, err)
 			return
 		}
 
+		if conflict != nil {
+			data := app.newTemplateData(r)
+			data["Character"] = character
+			data["Notes"] = notes
+			data["BaseNotes"] = base
+			data["TheirNotes"] = conflict.Theirs
+			data["Revision"] = database.NotesHash(conflict.Theirs)
+			data["Conflicts"] = conflict.Chunks
+
+			err = response.Partial(w, http.StatusConflict, data, nil, "partials/notes_conflict.tmpl", "partial:notes_conflict")
+			if err != nil {
+				app.serverError(w, r, err)
+			}
+
+			return
+		}
+
+		app.renderNotesDisplay(w, r, character, saved)
+	}
+}
+
+func (app *application) characterNotes(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+	app.renderNotesDisplay(w, r, character, character.Notes)
+}
+
+func (app *application) renderNotesDisplay(w http.ResponseWriter, r *http.Request, character *database.Character, notes string) {
+	htmlNotes, err := app.renderNotes(r, character, notes)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["CharacterAccess"] = contextGetCharacterAccess(r)
+	data["HTMLNotes"] = htmlNotes
+
+	err = response.Partial(w, http.StatusOK, data, nil, "partials/notes_display.tmpl", "partial:notes_display")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterNotesHistory(w http.ResponseWriter, r *http.Request) {
+	app.renderNotesHistory(w, r, contextGetCharacter(r), http.StatusOK, "")
+}
+
+func (app *application) renderNotesHistory(w http.ResponseWriter, r *http.Request, character *database.Character, status int, message string) {
+	revisions, err := app.db.GetNotesRevisions(character.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["CharacterAccess"] = contextGetCharacterAccess(r)
+	data["Revisions"] = notesRevisionViews(revisions)
+	data["Error"] = message
+
+	err = response.Partial(w, status, data, nil, "partials/notes_history.tmpl", "partial:notes_history")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterNotesRestore(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	revisionID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("revision"))
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	revision, err := app.db.GetNotesRevision(character.ID, revisionID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if revision == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	// Restoring replaces the notes wholesale: base them on what is stored now
+	// rather than merging with edits made since the revision.
+	saved, conflict, err := app.saveCharacterNotes(contextGetAuthenticatedUser(r), character.ID, character.Notes, revision.Notes)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if conflict != nil {
+		app.renderNotesHistory(w, r, character, http.StatusConflict, "Les notes ont été modifiées entre-temps, réessayez.")
+		return
+	}
+
+	app.renderNotesDisplay(w, r, character, saved)
+}
+
+func (app *application) characterCounterChange(counter database.Counter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		character := contextGetCharacter(r)
+
+		var form struct {
+			Operation string              `form:"Operation"`
+			Amount    int                 `form:"Amount"`
+			Validator validator.Validator `form:"-"`
+		}
+
+		err := request.DecodePostForm(r, &form)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+
+		maxValue := counterMax(character, counter)
+
+		form.Validator.CheckField(validator.In(form.Operation, "increment", "decrement", "set"), "Amount", "Unknown operation")
+		form.Validator.CheckField(validator.Between(form.Amount, 0, maxValue), "Amount", fmt.Sprintf("Must be between 0 and %d", maxValue))
+
+		status := http.StatusOK
+
+		if form.Validator.HasErrors() {
+			status = http.StatusUnprocessableEntity
+		} else {
+			switch form.Operation {
+			case "increment":
+				_, err = app.db.AdjustCharacterCounter(character.ID, counter, form.Amount, maxValue)
+			case "decrement":
+				_, err = app.db.AdjustCharacterCounter(character.ID, counter, -form.Amount, maxValue)
+			case "set":
+				err = app.db.SetCharacterCounter(character.ID, counter, form.Amount)
+			}
+
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			character, err = app.db.GetCharacter(character.ID)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+
+		counters := characterCounters(character)
+		for i := range counters {
+			if counters[i].Name == counter {
+				counters[i].Error = form.Validator.FieldErrors["Amount"]
+			}
+		}
+
 		data := app.newTemplateData(r)
 		data["Character"] = character
-		data["HTMLNotes"] = mdToHTML(form.Notes)
+		data["CharacterAccess"] = contextGetCharacterAccess(r)
+		data["Counters"] = counters
 
-		err = response.Partial(w, http.StatusOK, data, nil, "partials/notes_display.tmpl", "partial:notes_display")
+		err = response.Partial(w, status, data, nil, "partials/counters.tmpl", "partial:counters")
 		if err != nil {
 			app.serverError(w, r, err)
 		}
 	}
 }
 
-func mdToHTML(md string) template.HTML {
-	// create markdown parser with extensions
-	extensions := parser.CommonExtensions | parser.AutoHeadingIDs | parser.NoEmptyLineBeforeBlock | parser.HardLineBreak
-	p := parser.NewWithExtensions(extensions)
+func (app *application) characterRoll(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	var form struct {
+		Expression string              `form:"Expression"`
+		Validator  validator.Validator `form:"-"`
+	}
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	form.Validator.CheckField(validator.NotBlank(form.Expression), "Expression", "Expression is required")
+
+	var result *dice.Result
+
+	if !form.Validator.HasErrors() {
+		result, err = app.rollForCharacter(contextGetAuthenticatedUser(r), character, form.Expression)
+
+		switch {
+		case errors.Is(err, dice.ErrInvalidExpression):
+			form.Validator.AddFieldError("Expression", err.Error())
+		case err != nil:
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	history, err := app.diceRollHistory(character)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if form.Validator.HasErrors() {
+		status = http.StatusUnprocessableEntity
+	}
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["CharacterAccess"] = contextGetCharacterAccess(r)
+	data["Form"] = form
+	data["Roll"] = result
+	data["RollHistory"] = history
+
+	err = response.Partial(w, status, data, nil, "partials/dice.tmpl", "partial:dice_roller")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterRollJSON(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	var input struct {
+		Expression string `json:"expression"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	result, err := app.rollForCharacter(contextGetAuthenticatedUser(r), character, input.Expression)
+
+	switch {
+	case errors.Is(err, dice.ErrInvalidExpression):
+		app.badRequest(w, r, err)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, result)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterRollHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := app.diceRollHistory(contextGetCharacter(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-	// create HTML renderer with extensions
-	htmlFlags := html.CommonFlags | html.HrefTargetBlank
-	opts := html.RendererOptions{Flags: htmlFlags}
-	renderer := html.NewRenderer(opts)
+	data := app.newTemplateData(r)
+	data["Character"] = contextGetCharacter(r)
+	data["RollHistory"] = history
 
-	return template.HTML(markdown.ToHTML([]byte(md), p, renderer))
+	err = response.Partial(w, http.StatusOK, data, nil, "partials/dice.tmpl", "partial:dice_history")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterCreate(w http.ResponseWriter, r *http.Request) {
+	user := contextGetAuthenticatedUser(r)
+
+	draft, err := app.characterDraft(user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	wizard := &characterWizard{Step: draft.Step, Draft: draft}
+
+	if s := r.URL.Query().Get("step"); s != "" {
+		wizard.Step, err = strconv.Atoi(s)
+		if err != nil || wizard.Step < wizardStepIdentity || wizard.Step > draft.Step {
+			app.badRequest(w, r, fmt.Errorf("invalid wizard step %q", s))
+			return
+		}
+	}
+
+	if r.Method == http.MethodGet {
+		app.renderCharacterWizard(w, r, wizard, http.StatusOK, nil)
+		return
+	}
+
+	err = request.DecodePostForm(r, draft)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if wizard.Step == wizardStepConfirm {
+		app.characterCreateConfirm(w, r, wizard)
+		return
+	}
+
+	err = app.validateCharacterDraft(user, draft, wizard.Step, &wizard.Validator)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if wizard.Validator.HasErrors() {
+		app.renderCharacterWizard(w, r, wizard, http.StatusUnprocessableEntity, nil)
+		return
+	}
+
+	wizard.Step++
+	draft.Step = max(draft.Step, wizard.Step)
+
+	if wizard.Step == wizardStepAbilities && draft.Rolled == nil {
+		draft.Rolled, err = rules.RollAbilityScores(app.diceRoller)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	err = app.saveCharacterDraft(user, draft)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	url := fmt.Sprintf("/characters/new?step=%d", wizard.Step)
+
+	if r.Header.Get("HX-Request") != "true" {
+		http.Redirect(w, r, url, http.StatusSeeOther)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("HX-Push-Url", url)
+
+	app.renderCharacterWizard(w, r, wizard, http.StatusOK, headers)
+}
+
+// characterCreateConfirm checks the whole draft again before creating the
+// character, sending the user back to the first step that is not valid.
+func (app *application) characterCreateConfirm(w http.ResponseWriter, r *http.Request, wizard *characterWizard) {
+	user := contextGetAuthenticatedUser(r)
+	draft := wizard.Draft
+
+	for step := wizardStepIdentity; step < wizardStepConfirm; step++ {
+		err := app.validateCharacterDraft(user, draft, step, &wizard.Validator)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if wizard.Validator.HasErrors() {
+			wizard.Step = step
+			app.renderCharacterWizard(w, r, wizard, http.StatusUnprocessableEntity, nil)
+
+			return
+		}
+	}
+
+	race, err := app.db.GetRace(draft.RaceID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	profile, err := app.db.GetProfile(draft.ProfileID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	id, err := app.db.InsertCharacter(draftCharacter(draft, race, profile))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.db.DeleteCharacterDraft(user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	url := fmt.Sprintf("/character/%d", id)
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", url)
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	http.Redirect(w, r, url, http.StatusSeeOther)
+}
+
+func (app *application) renderCharacterWizard(w http.ResponseWriter, r *http.Request, wizard *characterWizard, status int, headers http.Header) {
+	var err error
+
+	wizard.Steps = wizardSteps
+	wizard.Genders = genders
+	wizard.Abilities = rules.Abilities
+	wizard.Budget = rules.PointBuyBudget
+
+	switch wizard.Step {
+	case wizardStepRace:
+		wizard.Races, err = app.db.GetRaces()
+	case wizardStepProfile:
+		wizard.Profiles, err = app.db.GetProfiles()
+	case wizardStepAbilities:
+		wizard.Pool = rules.StandardArray
+		if wizard.Draft.AbilityMethod == rules.MethodRoll {
+			wizard.Pool = wizard.Draft.Rolled
+		}
+	case wizardStepConfirm:
+		var (
+			race    *database.Race
+			profile *database.Profile
+		)
+
+		race, err = app.db.GetRace(wizard.Draft.RaceID)
+		if err == nil {
+			profile, err = app.db.GetProfile(wizard.Draft.ProfileID)
+		}
+
+		if race != nil && profile != nil {
+			wizard.Character = draftCharacter(wizard.Draft, race, profile)
+		}
+	}
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Wizard"] = wizard
+
+	if r.Header.Get("HX-Request") == "true" {
+		err = response.Partial(w, status, data, headers, "partials/character_wizard.tmpl", "partial:character_wizard")
+	} else {
+		err = response.Page(w, status, data, "pages/character_create.tmpl")
+	}
+
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterCreateCancel(w http.ResponseWriter, r *http.Request) {
+	err := app.db.DeleteCharacterDraft(contextGetAuthenticatedUser(r).ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/")
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+type levelUpForm struct {
+	Level        int                 `form:"Level"`
+	CapabilityID int                 `form:"CapabilityID"`
+	HealthMethod rules.HealthMethod  `form:"HealthMethod"`
+	Validator    validator.Validator `form:"-"`
+}
+
+func (app *application) characterLevelUp(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	form := levelUpForm{Level: character.Level + 1, HealthMethod: rules.HealthAverage}
+
+	choices, err := app.levelUpChoices(character)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	levelUps, err := app.db.GetLevelUps(character.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["Choices"] = choices
+	data["LevelUps"] = levelUps
+	data["Form"] = form
+
+	err = response.Page(w, http.StatusOK, data, "pages/level_up.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// characterLevelUpPreview shows what the character gains with the chosen
+// options; characterLevelUpApply applies the same computation.
+func (app *application) characterLevelUpPreview(w http.ResponseWriter, r *http.Request) {
+	app.handleLevelUp(w, r, false)
+}
+
+func (app *application) characterLevelUpApply(w http.ResponseWriter, r *http.Request) {
+	app.handleLevelUp(w, r, true)
+}
+
+func (app *application) handleLevelUp(w http.ResponseWriter, r *http.Request, apply bool) {
+	character := contextGetCharacter(r)
+
+	var form levelUpForm
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	choices, err := app.levelUpChoices(character)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["Choices"] = choices
+	data["Form"] = &form
+
+	render := func(status int) {
+		err := response.Partial(w, status, data, nil, "partials/level_up.tmpl", "partial:level_up")
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+	}
+
+	if form.Level != character.Level+1 {
+		form.Level = character.Level + 1
+		form.Validator.AddError("The character changed level, check the preview again")
+		render(http.StatusConflict)
+
+		return
+	}
+
+	var capability *database.Capability
+
+	for i := range choices {
+		if choices[i].ID == form.CapabilityID {
+			capability = &choices[i]
+		}
+	}
+
+	form.Validator.CheckField(capability != nil || len(choices) == 0, "CapabilityID", "Choose a capability")
+	form.Validator.CheckField(slices.Contains(rules.HealthMethods, form.HealthMethod), "HealthMethod", "Choose how to gain health")
+
+	if form.Validator.HasErrors() {
+		render(http.StatusUnprocessableEntity)
+		return
+	}
+
+	hitDie, err := app.levelUpHitDie(character, form.HealthMethod)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	levelUp := rules.NextLevel(character, hitDie, capability)
+
+	if !apply {
+		data["Preview"] = levelUp
+		render(http.StatusOK)
+
+		return
+	}
+
+	record := &database.LevelUp{
+		CharacterID:  character.ID,
+		UserID:       contextGetAuthenticatedUser(r).ID,
+		Level:        levelUp.Level,
+		HealthMethod: string(form.HealthMethod),
+		HitDie:       levelUp.HitDie,
+		HealthGain:   levelUp.HealthGain,
+		ManaGain:     levelUp.ManaGain,
+	}
+
+	if capability != nil {
+		record.CapabilityID = &capability.ID
+		record.CapabilityName = capability.Name
+	}
+
+	applied, err := app.db.LevelUpCharacter(record, levelUp.ManaMax)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !applied {
+		form.Validator.AddError("The character changed level, check the preview again")
+		render(http.StatusConflict)
+
+		return
+	}
+
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/character/%d", character.ID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) catalogIndex(w http.ResponseWriter, r *http.Request) {
+	cat, err := app.catalog.Get()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Catalog"] = cat
+
+	err = response.Page(w, http.StatusOK, data, "pages/catalog.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) catalogPaths(w http.ResponseWriter, r *http.Request) {
+	app.renderCatalogList(w, r, "paths")
+}
+
+func (app *application) catalogCapabilities(w http.ResponseWriter, r *http.Request) {
+	app.renderCatalogList(w, r, "capabilities")
+}
+
+func (app *application) renderCatalogList(w http.ResponseWriter, r *http.Request, list string) {
+	var filter catalog.Filter
+
+	err := request.DecodeQueryString(r, &filter)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	cat, err := app.catalog.Get()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Catalog"] = cat
+	data["Filter"] = filter
+	data["Paths"] = cat.FilterPaths(filter)
+	data["Capabilities"] = cat.FilterCapabilities(filter)
+	data["FilterURL"] = "/catalog/" + list
+	data["SpellFilter"] = list == "capabilities"
+
+	if r.Header.Get("HX-Request") == "true" {
+		err = response.Partial(w, http.StatusOK, data, nil, "partials/catalog_"+list+".tmpl", "partial:catalog_"+list)
+	} else {
+		err = response.Page(w, http.StatusOK, data, "pages/catalog_"+list+".tmpl")
+	}
+
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) catalogPath(w http.ResponseWriter, r *http.Request) {
+	path, err := app.catalogPathFromRequest(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if path == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Path"] = path
+
+	err = response.Page(w, http.StatusOK, data, "pages/catalog_path.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) catalogPathFromRequest(r *http.Request) (*catalog.Path, error) {
+	id, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil {
+		return nil, nil
+	}
+
+	cat, err := app.catalog.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return cat.Path(id), nil
+}
+
+func (app *application) catalogJSON(list func(*catalog.Catalog, catalog.Filter) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filter catalog.Filter
+
+		err := request.DecodeQueryString(r, &filter)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+
+		cat, err := app.catalog.Get()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		err = response.JSON(w, http.StatusOK, list(cat, filter))
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+	}
+}
+
+func (app *application) catalogPathJSON(w http.ResponseWriter, r *http.Request) {
+	path, err := app.catalogPathFromRequest(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if path == nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, path)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterInventoryAdd(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	var form inventoryItemForm
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	item := &database.InventoryItem{CharacterID: character.ID}
+
+	err = app.inventoryItem(&form, item)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if form.Validator.HasErrors() {
+		app.renderInventory(w, r, character, &form, http.StatusUnprocessableEntity)
+		return
+	}
+
+	_, err = app.db.InsertInventoryItem(item)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderInventory(w, r, character, nil, http.StatusOK)
+}
+
+// characterInventoryImport creates the inventory from the free-text
+// equipment of the Django sheet. Importing twice does nothing.
+func (app *application) characterInventoryImport(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	err := app.db.ImportEquipment(character.ID, character.Equipment, rules.ParseEquipment(character.Equipment))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderInventory(w, r, character, nil, http.StatusOK)
+}
+
+func (app *application) characterInventoryUpdate(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	item, ok := app.inventoryItemFromRequest(w, r, character)
+	if !ok {
+		return
+	}
+
+	var form inventoryItemForm
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = app.inventoryItem(&form, item)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if form.Validator.HasErrors() {
+		app.renderInventory(w, r, character, &form, http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = app.db.UpdateInventoryItem(item)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderInventory(w, r, character, nil, http.StatusOK)
+}
+
+func (app *application) characterInventoryDelete(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	item, ok := app.inventoryItemFromRequest(w, r, character)
+	if !ok {
+		return
+	}
+
+	err := app.db.DeleteInventoryItem(item)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderInventory(w, r, character, nil, http.StatusOK)
+}
+
+func (app *application) inventoryItemFromRequest(w http.ResponseWriter, r *http.Request, character *database.Character) (*database.InventoryItem, bool) {
+	itemID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("item"))
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	item, err := app.db.GetInventoryItem(character.ID, itemID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil, false
+	}
+
+	if item == nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	return item, true
+}
+
+// renderInventory renders the inventory along with the character's defense,
+// which equipped armor and shields change.
+func (app *application) renderInventory(w http.ResponseWriter, r *http.Request, character *database.Character, form *inventoryItemForm, status int) {
+	character, err := app.db.GetCharacter(character.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	inventory, err := app.characterInventory(character)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["CharacterAccess"] = contextGetCharacterAccess(r)
+	data["Inventory"] = inventory
+	data["InventoryForm"] = form
+	data["DefenseSwap"] = true
+
+	err = response.Partial(w, status, data, nil, "partials/inventory.tmpl", "partial:inventory")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterMoney(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	var form struct {
+		Operation string              `form:"Operation"`
+		PP        int                 `form:"PP"`
+		PO        int                 `form:"PO"`
+		PA        int                 `form:"PA"`
+		PC        int                 `form:"PC"`
+		Reason    string              `form:"Reason"`
+		Validator validator.Validator `form:"-"`
+	}
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	amount := rules.Purse{PP: form.PP, PO: form.PO, PA: form.PA, PC: form.PC}
+	before := rules.CharacterPurse(character)
+	after := before
+
+	form.Validator.CheckField(validator.In(form.Operation, "gain", "spend", "exchange"), "Amount", "Unknown operation")
+	form.Validator.CheckField(min(form.PP, form.PO, form.PA, form.PC) >= 0, "Amount", "Amounts must not be negative")
+	form.Validator.CheckField(len(form.Reason) <= 200, "Reason", "Reason is too long")
+
+	switch form.Operation {
+	case "gain":
+		form.Validator.CheckField(amount.Value() > 0, "Amount", "Amount is required")
+		after = before.Add(amount)
+	case "spend":
+		form.Validator.CheckField(amount.Value() > 0, "Amount", "Amount is required")
+
+		after, err = before.Pay(amount.Value())
+		if errors.Is(err, rules.ErrInsufficientFunds) {
+			form.Validator.AddFieldError("Amount", "Not enough money")
+		}
+	case "exchange":
+		after = rules.Exchange(before.Value())
+	}
+
+	status := http.StatusOK
+
+	if form.Validator.HasErrors() {
+		status = http.StatusUnprocessableEntity
+	} else {
+		updated, err := app.db.UpdateMoney(character.ID, contextGetAuthenticatedUser(r).ID, database.Money(before), database.Money(after), strings.TrimSpace(form.Reason))
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if !updated {
+			status = http.StatusConflict
+			form.Validator.AddFieldError("Amount", "The purse changed in the meantime, check it and try again")
+		}
+
+		character, err = app.db.GetCharacter(character.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	transactions, err := app.db.GetMoneyTransactions(character.ID, moneyTransactionsLength)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["CharacterAccess"] = contextGetCharacterAccess(r)
+	data["MoneyTransactions"] = transactions
+	data["MoneyError"] = form.Validator.FieldErrors["Amount"] + form.Validator.FieldErrors["Reason"]
+
+	err = response.Partial(w, status, data, nil, "partials/money.tmpl", "partial:money")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) characterExport(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	capabilities, err := app.db.GetCharacterCapabilities(character.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	inventory, err := app.characterInventory(character)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var items []database.InventoryItem
+	for _, entry := range inventory.Entries {
+		items = append(items, entry.InventoryItem)
+	}
+
+	headers := make(http.Header)
+	headers.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, catalog.Slug(character.Name)))
+
+	err = response.JSONWithHeaders(w, http.StatusOK, charjson.New(character, capabilities, items, time.Now().UTC()), headers)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+type characterImportForm struct {
+	Document string `form:"Document"`
+	Name     string `form:"Name"`
+	Confirm  bool   `form:"Confirm"`
+}
+
+// characterImport previews the uploaded document, then creates the
+// character once the preview is confirmed. The document is sent back with
+// the confirmation rather than kept on the server.
+func (app *application) characterImport(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+
+	if r.Method == http.MethodGet {
+		err := response.Page(w, http.StatusOK, data, "pages/character_import.tmpl")
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err := r.ParseMultipartForm(characterImportMaxBytes)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var form characterImportForm
+
+	err = request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	imp := &characterImport{Document: form.Document}
+
+	file, header, err := r.FormFile("File")
+	switch {
+	case err == nil:
+		defer file.Close()
+
+		if header.Size > characterImportMaxBytes {
+			imp.Validator.AddError(fmt.Sprintf("File must not be larger than %d bytes", characterImportMaxBytes))
+			break
+		}
+
+		content, err := io.ReadAll(file)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		imp.Document = string(content)
+	case !errors.Is(err, http.ErrMissingFile) && !errors.Is(err, http.ErrNotMultipart):
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if imp.Document == "" && !imp.Validator.HasErrors() {
+		imp.Validator.AddError("A file is required")
+	}
+
+	if !imp.Validator.HasErrors() {
+		document, err := charjson.Decode(strings.NewReader(imp.Document))
+		if err != nil {
+			imp.Validator.AddError(err.Error())
+		} else {
+			if form.Name != "" {
+				document.Character.Name = form.Name
+			}
+
+			err = app.resolveCharacterImport(contextGetAuthenticatedUser(r), document, imp)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+	}
+
+	if imp.Validator.HasErrors() || !form.Confirm {
+		status := http.StatusOK
+		if imp.Validator.HasErrors() {
+			status = http.StatusUnprocessableEntity
+		}
+
+		data["Import"] = imp
+
+		err = response.Page(w, status, data, "pages/character_import.tmpl")
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	id, err := app.db.ImportCharacter(imp.Character, imp.CapabilityIDs, imp.Items)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/character/%d", id), http.StatusSeeOther)
+}
+
+func (app *application) characterSheetPDF(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	size := sheetpdf.A4
+
+	if paper := r.URL.Query().Get("paper"); paper != "" {
+		i := slices.IndexFunc(sheetpdf.PaperSizes, func(s sheetpdf.PaperSize) bool { return strings.EqualFold(string(s), paper) })
+		if i < 0 {
+			app.badRequest(w, r, fmt.Errorf("invalid paper size %q", paper))
+			return
+		}
+
+		size = sheetpdf.PaperSizes[i]
+	}
+
+	sheet := &sheetpdf.Sheet{Character: character}
+
+	if contextGetCharacterAccess(r).Can(authz.ActionViewNotes) {
+		sheet.Notes = app.markdown.Render(character.Notes)
+	}
+
+	capabilities, err := app.db.GetCharacterCapabilities(character.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	for _, capability := range capabilities {
+		sheet.Capabilities = append(sheet.Capabilities, sheetpdf.Capability{Capability: capability, Description: app.markdown.Render(capability.Description)})
+	}
+
+	inventory, err := app.characterInventory(character)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	for _, entry := range inventory.Entries {
+		sheet.Inventory = append(sheet.Inventory, sheetpdf.Item{InventoryItem: entry.InventoryItem, Nested: entry.Nested})
+	}
+
+	var buf bytes.Buffer
+
+	err = sheetpdf.Write(&buf, sheet, size)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, catalog.Slug(character.Name)))
+	buf.WriteTo(w)
+}
+
+func (app *application) campaigns(w http.ResponseWriter, r *http.Request) {
+	user := contextGetAuthenticatedUser(r)
+
+	var form campaignForm
+
+	status := http.StatusOK
+
+	if r.Method == http.MethodPost {
+		err := request.DecodePostForm(r, &form)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+
+		form.Name = strings.TrimSpace(form.Name)
+		form.Validator.CheckField(form.Name != "", "Name", "Name is required")
+		form.Validator.CheckField(utf8.RuneCountInString(form.Name) <= 100, "Name", "Name is too long")
+
+		if !form.Validator.HasErrors() {
+			id, err := app.db.InsertCampaign(form.Name, user.ID)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			http.Redirect(w, r, fmt.Sprintf("/campaigns/%d", id), http.StatusSeeOther)
+			return
+		}
+
+		status = http.StatusUnprocessableEntity
+	}
+
+	campaigns, err := app.db.GetUserCampaigns(user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Campaigns"] = campaigns
+	data["Form"] = form
+
+	err = response.Page(w, status, data, "pages/campaigns.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) campaignDetail(w http.ResponseWriter, r *http.Request) {
+	app.renderCampaign(w, r, http.StatusOK, campaignInvitationForm{}, nil)
+}
+
+func (app *application) renderCampaign(w http.ResponseWriter, r *http.Request, status int, form campaignInvitationForm, effectForm *effectForm) {
+	view, err := app.campaignView(contextGetAuthenticatedUser(r), contextGetCampaign(r), contextGetCampaignAccess(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	view.Form = form
+	view.EffectForm = effectForm
+
+	data := app.newTemplateData(r)
+	data["Campaign"] = view
+	data["RestPath"] = fmt.Sprintf("/campaigns/%d/rest", view.Campaign.ID)
+
+	err = response.Page(w, status, data, "pages/campaign.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) campaignInvite(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+
+	var form campaignInvitationForm
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	form.Email = strings.ToLower(strings.TrimSpace(form.Email))
+	form.Validator.CheckField(form.Email != "", "Email", "Email is required")
+	form.Validator.CheckField(validator.Matches(form.Email, validator.RgxEmail), "Email", "Must be a valid email address")
+
+	invitee, err := app.db.GetUserByEmail(form.Email)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if invitee != nil {
+		isMember, err := app.db.IsCampaignMember(campaign.ID, invitee.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		form.Validator.CheckField(!isMember, "Email", "This user is already a member")
+		form.Validator.CheckField(invitee.ID != campaign.GameMasterID, "Email", "This user is the game master")
+	}
+
+	if form.Validator.HasErrors() {
+		app.renderCampaign(w, r, http.StatusUnprocessableEntity, form, nil)
+		return
+	}
+
+	plaintextToken, err := token.New()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.db.InsertCampaignInvitation(token.Hash(plaintextToken), campaign.ID, form.Email, contextGetAuthenticatedUser(r).ID, campaignInvitationTTL)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newEmailData()
+	data["PlaintextToken"] = plaintextToken
+	data["CampaignName"] = campaign.Name
+	data["GameMasterName"] = campaign.GameMasterName
+
+	err = app.mailer.Send(form.Email, data, "campaign-invitation.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/campaigns/%d", campaign.ID), http.StatusSeeOther)
+}
+
+func (app *application) campaignMemberRemove(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+
+	userID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("user"))
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.db.RemoveCampaignMember(campaign.ID, userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/campaigns/%d", campaign.ID), http.StatusSeeOther)
+}
+
+// campaignCharacterAdd brings one of the user's characters in the campaign
+// they play or run.
+func (app *application) campaignCharacterAdd(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+	access := contextGetCampaignAccess(r)
+
+	var form struct {
+		CharacterID int `form:"CharacterID"`
+	}
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	character, err := app.db.GetCharacter(form.CharacterID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if character == nil || character.PlayerID != contextGetAuthenticatedUser(r).ID || !(access.IsMember || access.IsGameMaster) {
+		app.forbidden(w, r)
+		return
+	}
+
+	err = app.db.AddCampaignCharacter(campaign.ID, character.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/campaigns/%d", campaign.ID), http.StatusSeeOther)
+}
+
+// campaignCharacterRemove takes a character out of the campaign, by its
+// player or by those who manage the campaign.
+func (app *application) campaignCharacterRemove(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+
+	characterID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("character"))
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	character, err := app.db.GetCharacter(characterID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if character == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	if character.PlayerID != contextGetAuthenticatedUser(r).ID && !contextGetCampaignAccess(r).Can(authz.ActionManageCampaign) {
+		app.forbidden(w, r)
+		return
+	}
+
+	err = app.db.RemoveCampaignCharacter(campaign.ID, character.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/campaigns/%d", campaign.ID), http.StatusSeeOther)
+}
+
+// campaignInvitation shows the invitation of the link to the user it was
+// sent to, who then accepts or declines it.
+func (app *application) campaignInvitation(w http.ResponseWriter, r *http.Request) {
+	invitation, plaintextToken, ok := app.campaignInvitationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Invitation"] = invitation
+	data["PlaintextToken"] = plaintextToken
+
+	err := response.Page(w, http.StatusOK, data, "pages/campaign_invitation.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) campaignInvitationAccept(w http.ResponseWriter, r *http.Request) {
+	invitation, _, ok := app.campaignInvitationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.db.AcceptCampaignInvitation(invitation, contextGetAuthenticatedUser(r).ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/campaigns/%d", invitation.CampaignID), http.StatusSeeOther)
+}
+
+func (app *application) campaignInvitationDecline(w http.ResponseWriter, r *http.Request) {
+	invitation, _, ok := app.campaignInvitationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.db.DeleteCampaignInvitation(invitation.HashedToken)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/campaigns", http.StatusSeeOther)
+}
+
+// campaignInvitationFromRequest only returns invitations sent to the email
+// of the user, writing the error response otherwise.
+func (app *application) campaignInvitationFromRequest(w http.ResponseWriter, r *http.Request) (*database.CampaignInvitation, string, bool) {
+	plaintextToken := httprouter.ParamsFromContext(r.Context()).ByName("plaintextToken")
+
+	invitation, err := app.db.GetCampaignInvitation(token.Hash(plaintextToken))
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil, "", false
+	}
+
+	if invitation == nil {
+		app.errorPage(w, r, http.StatusNotFound, "This invitation is invalid or has expired")
+		return nil, "", false
+	}
+
+	if !strings.EqualFold(invitation.Email, contextGetAuthenticatedUser(r).Email) {
+		app.errorPage(w, r, http.StatusForbidden, "This invitation was sent to another email address")
+		return nil, "", false
+	}
+
+	return invitation, plaintextToken, true
+}
+
+func (app *application) campaignEncounterCreate(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+
+	var form struct {
+		Name string `form:"Name"`
+	}
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	if !validator.MaxRunes(form.Name, 100) {
+		app.errorPage(w, r, http.StatusUnprocessableEntity, "Name is too long")
+		return
+	}
+
+	if form.Name == "" {
+		encounters, err := app.db.GetCampaignEncounters(campaign.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		form.Name = fmt.Sprintf("Rencontre n°%d", len(encounters)+1)
+	}
+
+	id, err := app.db.InsertEncounter(campaign.ID, form.Name)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/campaigns/%d/encounters/%d", campaign.ID, id), http.StatusSeeOther)
+}
+
+func (app *application) encounterDetail(w http.ResponseWriter, r *http.Request) {
+	view, err := app.encounterView(contextGetCampaign(r), contextGetEncounter(r), contextGetCampaignAccess(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Encounter"] = view
+
+	err = response.Page(w, http.StatusOK, data, "pages/encounter.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) encounterTracker(w http.ResponseWriter, r *http.Request) {
+	app.renderEncounterTracker(w, r, http.StatusOK, combatantForm{})
+}
+
+// renderEncounterTracker reloads the encounter, which the handlers may have
+// changed since the middleware loaded it.
+func (app *application) renderEncounterTracker(w http.ResponseWriter, r *http.Request, status int, form combatantForm) {
+	encounter, err := app.db.GetEncounter(contextGetEncounter(r).ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	view, err := app.encounterView(contextGetCampaign(r), encounter, contextGetCampaignAccess(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	view.Form = form
+
+	data := app.newTemplateData(r)
+	data["Encounter"] = view
+
+	err = response.Partial(w, status, data, nil, "partials/encounter.tmpl", "partial:encounter")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// encounterCombatantAdd adds a character of the campaign, or a non-player
+// character when no character is given.
+func (app *application) encounterCombatantAdd(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+	encounter := contextGetEncounter(r)
+
+	var form combatantForm
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	combatant := &database.Combatant{EncounterID: encounter.ID}
+
+	if form.CharacterID != 0 {
+		view, err := app.encounterView(campaign, encounter, contextGetCampaignAccess(r))
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		i := slices.IndexFunc(view.Available, func(c database.CampaignCharacter) bool { return c.ID == form.CharacterID })
+		form.Validator.CheckField(i >= 0, "CharacterID", "This character is not in the campaign or already fights")
+
+		if i >= 0 {
+			combatant.CharacterID = &view.Available[i].ID
+			combatant.Name = view.Available[i].Name
+		}
+	} else {
+		form.Name = strings.TrimSpace(form.Name)
+		form.Validator.CheckField(form.Name != "", "Name", "Name is required")
+		form.Validator.CheckField(validator.MaxRunes(form.Name, 100), "Name", "Name is too long")
+		form.Validator.CheckField(validator.Between(form.Initiative, 0, 50), "Initiative", "Must be between 0 and 50")
+		form.Validator.CheckField(validator.Between(form.Defense, 0, 50), "Defense", "Must be between 0 and 50")
+		form.Validator.CheckField(validator.Between(form.Reduction, 0, 50), "Reduction", "Must be between 0 and 50")
+		form.Validator.CheckField(validator.Between(form.Health, 1, 999), "Health", "Must be between 1 and 999")
+
+		combatant.Name = form.Name
+		combatant.InitiativeBonus = form.Initiative
+		combatant.Defense = form.Defense
+		combatant.DamageReduction = form.Reduction
+		combatant.HealthMax = form.Health
+	}
+
+	if form.Validator.HasErrors() {
+		app.renderEncounterTracker(w, r, http.StatusUnprocessableEntity, form)
+		return
+	}
+
+	_, err = app.db.InsertCombatant(combatant)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderEncounterTracker(w, r, http.StatusOK, combatantForm{})
+}
+
+// encounterCombatantRemove passes the turn on first when the combatant is
+// the one playing.
+func (app *application) encounterCombatantRemove(w http.ResponseWriter, r *http.Request) {
+	encounter := contextGetEncounter(r)
+
+	combatant, ok := app.combatantFromRequest(w, r, encounter)
+	if !ok {
+		return
+	}
+
+	if encounter.CurrentCombatantID != nil && *encounter.CurrentCombatantID == combatant.ID {
+		combatants, err := app.db.GetCombatants(encounter.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		round, current := nextTurn(encounter, combatants)
+		if current != nil && *current == combatant.ID {
+			current = nil
+		}
+
+		err = app.setEncounterTurn(encounter, round, current)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	err := app.db.DeleteCombatant(encounter.ID, combatant.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderEncounterTracker(w, r, http.StatusOK, combatantForm{})
+}
+
+// encounterCombatantHealth applies damage or healing, to the sheet of
+// characters and to the encounter for the others.
+func (app *application) encounterCombatantHealth(w http.ResponseWriter, r *http.Request) {
+	combatant, ok := app.combatantFromRequest(w, r, contextGetEncounter(r))
+	if !ok {
+		return
+	}
+
+	var form struct {
+		Operation string              `form:"Operation"`
+		Amount    int                 `form:"Amount"`
+		Validator validator.Validator `form:"-"`
+	}
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	form.Validator.CheckField(validator.In(form.Operation, "damage", "heal"), "Amount", "Unknown operation")
+	form.Validator.CheckField(validator.Between(form.Amount, 1, 999), "Amount", "Must be between 1 and 999")
+
+	if form.Validator.HasErrors() {
+		app.renderEncounterTracker(w, r, http.StatusUnprocessableEntity, combatantForm{Validator: form.Validator})
+		return
+	}
+
+	delta := form.Amount
+	if form.Operation == "damage" {
+		delta = -delta
+	}
+
+	if combatant.CharacterID != nil {
+		character, err := app.db.GetCharacter(*combatant.CharacterID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if character == nil {
+			app.notFound(w, r)
+			return
+		}
+
+		_, err = app.db.AdjustCharacterCounter(character.ID, database.CounterHealth, delta, counterMax(character, database.CounterHealth))
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	} else {
+		_, err = app.db.AdjustCombatantHealth(combatant.ID, delta)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.renderEncounterTracker(w, r, http.StatusOK, combatantForm{})
+}
+
+// encounterInitiative rolls the initiative of every combatant before the
+// fight starts, and of the combatants who joined it afterwards.
+func (app *application) encounterInitiative(w http.ResponseWriter, r *http.Request) {
+	encounter := contextGetEncounter(r)
+
+	combatants, err := app.db.GetCombatants(encounter.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	for _, combatant := range combatants {
+		if encounter.Round > 0 && combatant.Initiative != nil {
+			continue
+		}
+
+		bonus := combatant.InitiativeBonus
+
+		if combatant.CharacterID != nil {
+			character, err := app.db.GetCharacter(*combatant.CharacterID)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			if character != nil {
+				bonus = rules.Compute(character).Initiative
+			}
+		}
+
+		initiative, err := rules.RollInitiative(app.diceRoller, bonus)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		err = app.db.SetCombatantInitiative(combatant.ID, bonus, initiative)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.renderEncounterTracker(w, r, http.StatusOK, combatantForm{})
+}
+
+func (app *application) encounterNextTurn(w http.ResponseWriter, r *http.Request) {
+	encounter := contextGetEncounter(r)
+
+	combatants, err := app.db.GetCombatants(encounter.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	round, current := nextTurn(encounter, combatants)
+
+	err = app.setEncounterTurn(encounter, round, current)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderEncounterTracker(w, r, http.StatusOK, combatantForm{})
+}
+
+func (app *application) encounterEnd(w http.ResponseWriter, r *http.Request) {
+	err := app.db.EndEncounter(contextGetEncounter(r).ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderEncounterTracker(w, r, http.StatusOK, combatantForm{})
+}
+
+func (app *application) combatantFromRequest(w http.ResponseWriter, r *http.Request, encounter *database.Encounter) (*database.Combatant, bool) {
+	combatantID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("combatant"))
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	combatant, err := app.db.GetCombatant(encounter.ID, combatantID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil, false
+	}
+
+	if combatant == nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	return combatant, true
+}
+
+func (app *application) characterEffectAdd(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	var form effectForm
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	effect := newEffect(&form, character.ID, contextGetAuthenticatedUser(r).ID)
+
+	if form.Validator.HasErrors() {
+		app.renderEffects(w, r, character, &form, http.StatusUnprocessableEntity)
+		return
+	}
+
+	_, err = app.db.InsertEffect(effect)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderEffects(w, r, character, nil, http.StatusOK)
+}
+
+func (app *application) characterEffectRemove(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	effectID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("effect"))
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.db.EndEffect(character.ID, effectID, database.EffectRemoved)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderEffects(w, r, character, nil, http.StatusOK)
+}
+
+// renderEffects renders the effects along with the combat statistics they
+// modify.
+func (app *application) renderEffects(w http.ResponseWriter, r *http.Request, character *database.Character, form *effectForm, status int) {
+	character, err := app.db.GetCharacter(character.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	history, err := app.db.GetEffectHistory(character.ID, effectHistoryLength)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["CharacterAccess"] = contextGetCharacterAccess(r)
+	data["Effects"] = effectViews(character.Effects)
+	data["EffectHistory"] = effectViews(history)
+	data["EffectForm"] = form
+	data["CombatSwap"] = true
+
+	err = response.NamedTemplate(w, status, data, "partial:effects", "partials/effects.tmpl", "partials/combat.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) campaignEffectAdd(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+
+	var form effectForm
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	effect := newEffect(&form, 0, contextGetAuthenticatedUser(r).ID)
+
+	if form.Validator.HasErrors() {
+		app.renderCampaign(w, r, http.StatusUnprocessableEntity, campaignInvitationForm{}, &form)
+		return
+	}
+
+	_, err = app.db.InsertCampaignEffect(campaign.ID, effect)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/campaigns/%d", campaign.ID), http.StatusSeeOther)
+}
+
+func (app *application) campaignEffectEnd(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+
+	effectID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("effect"))
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.db.EndCampaignEffect(campaign.ID, effectID, database.EffectRemoved)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/campaigns/%d", campaign.ID), http.StatusSeeOther)
+}
+
+// campaignEffectExclude excludes a character from the effect, or includes
+// it again.
+func (app *application) campaignEffectExclude(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+
+	effectID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("effect"))
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	var form struct {
+		CharacterID int  `form:"CharacterID"`
+		Excluded    bool `form:"Excluded"`
+	}
+
+	err = request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if form.Excluded {
+		err = app.db.ExcludeFromCampaignEffect(campaign.ID, effectID, form.CharacterID)
+	} else {
+		err = app.db.IncludeInCampaignEffect(campaign.ID, effectID, form.CharacterID)
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/campaigns/%d", campaign.ID), http.StatusSeeOther)
+}
+
+func (app *application) characterRest(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	var form restForm
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	validateRestForm(&form)
+
+	if form.Validator.HasErrors() {
+		app.renderCharacterRest(w, r, nil, &form, http.StatusUnprocessableEntity)
+		return
+	}
+
+	rc, err := app.planRest(character, form.Kind, form.Spend)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	id, err := app.db.InsertRest(&database.Rest{
+		UserID:     contextGetAuthenticatedUser(r).ID,
+		Kind:       string(form.Kind),
+		Characters: []database.RestCharacter{rc},
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	rest, err := app.db.GetRest(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderCharacterRest(w, r, rest, nil, http.StatusOK)
+}
+
+func (app *application) characterRestUndo(w http.ResponseWriter, r *http.Request) {
+	character := contextGetCharacter(r)
+
+	rest, ok := app.restFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if rest.CampaignID != nil || len(rest.Characters) != 1 || rest.Characters[0].CharacterID != character.ID {
+		app.notFound(w, r)
+		return
+	}
+
+	undone, err := app.db.UndoRest(rest, time.Now().Add(-restUndoWindow))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !undone {
+		form := &restForm{}
+		form.Validator.AddError("This rest can no longer be undone")
+		app.renderCharacterRest(w, r, rest, form, http.StatusConflict)
+		return
+	}
+
+	rest, err = app.db.GetRest(rest.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderCharacterRest(w, r, rest, nil, http.StatusOK)
+}
+
+// renderCharacterRest renders the summary of the rest along with the
+// counters and the effects it changed.
+func (app *application) renderCharacterRest(w http.ResponseWriter, r *http.Request, rest *database.Rest, form *restForm, status int) {
+	character, err := app.db.GetCharacter(contextGetCharacter(r).ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	history, err := app.db.GetEffectHistory(character.ID, effectHistoryLength)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["CharacterAccess"] = contextGetCharacterAccess(r)
+	data["Counters"] = characterCounters(character)
+	data["Effects"] = effectViews(character.Effects)
+	data["EffectHistory"] = effectViews(history)
+	data["RestPath"] = fmt.Sprintf("/character/%d/rest", character.ID)
+	data["RestForm"] = form
+	data["CountersSwap"] = true
+	data["EffectsSwap"] = true
+	data["CombatSwap"] = true
+
+	if rest != nil {
+		data["Rest"] = newRestView(rest)
+	}
+
+	err = response.NamedTemplate(w, status, data, "partial:rest",
+		"partials/rest.tmpl", "partials/counters.tmpl", "partials/effects.tmpl", "partials/combat.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// campaignRest rests every character of the campaign at once.
+func (app *application) campaignRest(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+
+	var form restForm
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	validateRestForm(&form)
+
+	characters, err := app.db.GetCampaignCharacters(campaign.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	form.Validator.Check(len(characters) > 0, "The campaign has no characters")
+
+	if form.Validator.HasErrors() {
+		app.renderCampaignRest(w, r, nil, &form, http.StatusUnprocessableEntity)
+		return
+	}
+
+	rest := &database.Rest{
+		CampaignID: &campaign.ID,
+		UserID:     contextGetAuthenticatedUser(r).ID,
+		Kind:       string(form.Kind),
+	}
+
+	for _, cc := range characters {
+		character, err := app.db.GetCharacter(cc.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if character == nil {
+			continue
+		}
+
+		rc, err := app.planRest(character, form.Kind, form.Spend)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		rest.Characters = append(rest.Characters, rc)
+	}
+
+	id, err := app.db.InsertRest(rest)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	rest, err = app.db.GetRest(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderCampaignRest(w, r, rest, nil, http.StatusOK)
+}
+
+func (app *application) campaignRestUndo(w http.ResponseWriter, r *http.Request) {
+	campaign := contextGetCampaign(r)
+
+	rest, ok := app.restFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if rest.CampaignID == nil || *rest.CampaignID != campaign.ID {
+		app.notFound(w, r)
+		return
+	}
+
+	undone, err := app.db.UndoRest(rest, time.Now().Add(-restUndoWindow))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !undone {
+		form := &restForm{}
+		form.Validator.AddError("This rest can no longer be undone")
+		app.renderCampaignRest(w, r, rest, form, http.StatusConflict)
+		return
+	}
+
+	rest, err = app.db.GetRest(rest.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderCampaignRest(w, r, rest, nil, http.StatusOK)
+}
+
+func (app *application) renderCampaignRest(w http.ResponseWriter, r *http.Request, rest *database.Rest, form *restForm, status int) {
+	data := app.newTemplateData(r)
+	data["RestPath"] = fmt.Sprintf("/campaigns/%d/rest", contextGetCampaign(r).ID)
+	data["RestForm"] = form
+
+	if rest != nil {
+		data["Rest"] = newRestView(rest)
+	}
+
+	err := response.Partial(w, status, data, nil, "partials/rest.tmpl", "partial:rest")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) restFromRequest(w http.ResponseWriter, r *http.Request) (*database.Rest, bool) {
+	restID, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("rest"))
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	rest, err := app.db.GetRest(restID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil, false
+	}
+
+	if rest == nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	return rest, true
+}
+
+// characterAttack rolls an attack of the character against a target and
+// posts it to the roll history of the campaign. The damage is written to
+// the health of the target when asked, which for another character takes
+// the right to change its counters.
+func (app *application) characterAttack(w http.ResponseWriter, r *http.Request) {
+	user := contextGetAuthenticatedUser(r)
+	character := contextGetCharacter(r)
+
+	var form attackForm
+
+	err := request.DecodePostForm(r, &form)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	view, err := app.attackView(character)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	weapon := attackWeapon{Name: "Attaque"}
+
+	if form.Weapon != "" {
+		var ok bool
+
+		weapon, ok = view.weapon(form.Weapon)
+		form.Validator.CheckField(ok, "Weapon", "Unknown weapon")
+	}
+
+	if weapon.Attack == "" {
+		weapon.Attack = form.Attack
+		form.Validator.CheckField(validator.In(form.Attack, rules.AttackKinds...), "Attack", "Unknown attack")
+	}
+
+	form.Damage = strings.TrimSpace(form.Damage)
+	if form.Damage != "" {
+		weapon.Damage = form.Damage
+	}
+
+	form.Validator.CheckField(weapon.Damage != "", "Damage", "Damage is required")
+
+	if form.Critical == 0 {
+		form.Critical = rules.CriticalThreshold
+	}
+
+	form.Validator.CheckField(validator.Between(form.Critical, 2, rules.AttackDie), "Critical", fmt.Sprintf("Must be between 2 and %d", rules.AttackDie))
+
+	target, ok := view.target(form.Target)
+	form.Validator.CheckField(ok, "Target", "Unknown target")
+
+	if ok && form.Apply && target.Character != nil {
+		access, err := app.characterAccess(user, target.Character)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		form.Validator.CheckField(access.Can(authz.ActionEditCounters), "Apply", "You cannot change the health of this character")
+	}
+
+	var attack *rules.Attack
+
+	if !form.Validator.HasErrors() {
+		stats := rules.Compute(character)
+
+		attack, err = rules.ResolveAttack(app.diceRoller, rules.DiceReferences(character), stats.AttackBonus(weapon.Attack), form.Critical,
+			target.Defense, weapon.Damage, target.Reduction)
+
+		switch {
+		case errors.Is(err, dice.ErrInvalidExpression):
+			form.Validator.AddFieldError("Damage", err.Error())
+		case err != nil:
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if form.Validator.HasErrors() {
+		app.renderAttack(w, r, view, &form, nil, http.StatusUnprocessableEntity)
+		return
+	}
+
+	summary := attackSummary(weapon.Name, target.Name, attack)
+
+	if form.Apply && attack.Dealt > 0 {
+		var health int
+
+		if target.Character != nil {
+			health, err = app.db.AdjustCharacterCounter(target.Character.ID, database.CounterHealth, -attack.Dealt, rules.Compute(target.Character).HealthMax)
+		} else {
+			health, err = app.db.AdjustCombatantHealth(target.Combatant.ID, -attack.Dealt)
+		}
+
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		summary += fmt.Sprintf(", %s à %d PV", target.Name, health)
+	}
+
+	err = app.recordAttack(user, character, view.CampaignID, attack, summary)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderAttack(w, r, view, &form, &summary, http.StatusOK)
+}
+
+func (app *application) renderAttack(w http.ResponseWriter, r *http.Request, view *attackView, form *attackForm, result *string, status int) {
+	character := contextGetCharacter(r)
+
+	history, err := app.diceRollHistory(character)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data["Character"] = character
+	data["CharacterAccess"] = contextGetCharacterAccess(r)
+	data["Attack"] = view
+	data["AttackForm"] = form
+	data["AttackResult"] = result
+	data["RollHistory"] = history
+	data["HistorySwap"] = true
+
+	err = response.NamedTemplate(w, status, data, "partial:attack", "partials/attack.tmpl", "partials/dice.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+	}
 }
@@ -1,9 +1,26 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"html/template"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/Crocmagnon/charasheet-go/internal/authz"
+	"github.com/Crocmagnon/charasheet-go/internal/charjson"
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+	"github.com/Crocmagnon/charasheet-go/internal/diff"
+	"github.com/Crocmagnon/charasheet-go/internal/markdown"
+	"github.com/Crocmagnon/charasheet-go/internal/rules"
+	"github.com/Crocmagnon/charasheet-go/internal/validator"
 	"github.com/Crocmagnon/charasheet-go/internal/version"
 	"github.com/justinas/nosurf"
 )
@@ -26,6 +43,317 @@ func (app *application) newEmailData() map[string]any {
 	return data
 }
 
+func (app *application) characterAccess(user *database.User, character *database.Character) (authz.Access, error) {
+	if user == nil {
+		return authz.Access{}, nil
+	}
+
+	isGameMaster, err := app.db.IsCharacterGameMaster(character.ID, user.ID)
+	if err != nil {
+		return authz.Access{}, err
+	}
+
+	isMember, err := app.db.IsCharacterCampaignMember(character.ID, user.ID)
+	if err != nil {
+		return authz.Access{}, err
+	}
+
+	access := authz.Access{
+		IsPlayer:     character.PlayerID == user.ID,
+		IsGameMaster: isGameMaster,
+		IsStaff:      user.IsStaff || user.IsSuperuser,
+		IsMember:     isMember,
+	}
+
+	return access, nil
+}
+
+func (app *application) campaignAccess(user *database.User, campaign *database.Campaign) (authz.Access, error) {
+	if user == nil {
+		return authz.Access{}, nil
+	}
+
+	isMember, err := app.db.IsCampaignMember(campaign.ID, user.ID)
+	if err != nil {
+		return authz.Access{}, err
+	}
+
+	access := authz.Access{
+		IsGameMaster: campaign.GameMasterID == user.ID,
+		IsStaff:      user.IsStaff || user.IsSuperuser,
+		IsMember:     isMember,
+	}
+
+	return access, nil
+}
+
+type counterView struct {
+	Name  database.Counter
+	Label string
+	Path  string
+	Value int
+	Max   int
+	Error string
+}
+
+var counterSlugs = map[database.Counter]string{
+	database.CounterHealth:         "health",
+	database.CounterMana:           "mana",
+	database.CounterRecoveryPoints: "recovery_points",
+	database.CounterLuckPoints:     "luck_points",
+}
+
+func characterCounters(character *database.Character) []counterView {
+	labels := map[database.Counter]string{
+		database.CounterHealth:         "Points de vie",
+		database.CounterMana:           "Points de mana",
+		database.CounterRecoveryPoints: "Points de récupération",
+		database.CounterLuckPoints:     "Points de chance",
+	}
+
+	values := map[database.Counter]int{
+		database.CounterHealth:         character.HealthRemaining,
+		database.CounterMana:           character.ManaRemaining,
+		database.CounterRecoveryPoints: character.RecoveryPointsRemaining,
+		database.CounterLuckPoints:     character.LuckPointsRemaining,
+	}
+
+	views := make([]counterView, 0, len(database.Counters))
+
+	for _, counter := range database.Counters {
+		views = append(views, counterView{
+			Name:  counter,
+			Label: labels[counter],
+			Path:  fmt.Sprintf("/character/%d/%s_change/", character.ID, counterSlugs[counter]),
+			Value: values[counter],
+			Max:   counterMax(character, counter),
+		})
+	}
+
+	return views
+}
+
+func counterMax(character *database.Character, counter database.Counter) int {
+	stats := rules.Compute(character)
+
+	switch counter {
+	case database.CounterHealth:
+		return stats.HealthMax
+	case database.CounterMana:
+		return stats.ManaMax
+	case database.CounterRecoveryPoints:
+		return stats.RecoveryPointsMax
+	case database.CounterLuckPoints:
+		return stats.LuckPointsMax
+	}
+
+	return 0
+}
+
+const diceRollHistoryLength = 20
+
+// rollForCharacter rolls the expression with the character's references and
+// stores the result in the shared roll history of its campaign.
+func (app *application) rollForCharacter(user *database.User, character *database.Character, input string) (*dice.Result, error) {
+	result, err := app.diceRoller.RollString(input, rules.DiceReferences(character))
+	if err != nil {
+		return nil, err
+	}
+
+	detail, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	campaignID, err := app.db.GetCharacterCampaignID(character.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = app.db.InsertDiceRoll(&database.DiceRoll{
+		CharacterID: character.ID,
+		CampaignID:  campaignID,
+		UserID:      user.ID,
+		Expression:  result.Expression,
+		Total:       result.Total,
+		Breakdown:   result.String(),
+		Detail:      string(detail),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (app *application) diceRollHistory(character *database.Character) ([]database.DiceRoll, error) {
+	campaignID, err := app.db.GetCharacterCampaignID(character.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.db.GetDiceRollHistory(character.ID, campaignID, diceRollHistoryLength)
+}
+
+const notesSaveAttempts = 3
+
+type notesConflict struct {
+	Theirs string
+	Chunks []notesConflictChunk
+}
+
+type notesConflictChunk struct {
+	Index  int
+	Ours   string
+	Theirs string
+}
+
+// saveCharacterNotes writes notes edited from base and records the revision.
+// When the stored notes changed meanwhile, both edits are merged; overlapping
+// edits are returned as a conflict for the user to resolve.
+func (app *application) saveCharacterNotes(user *database.User, characterID int, base, notes string) (string, *notesConflict, error) {
+	for attempt := 0; ; attempt++ {
+		saved, err := app.db.SaveCharacterNotes(characterID, user.ID, base, notes, app.config.notes.revisionsMax)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if saved {
+			return notes, nil, nil
+		}
+
+		character, err := app.db.GetCharacter(characterID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if character == nil {
+			return "", nil, fmt.Errorf("character %d not found", characterID)
+		}
+
+		if attempt+1 >= notesSaveAttempts {
+			return "", nil, errors.New("notes kept changing while saving")
+		}
+
+		merge := diff.Merge3(base, notes, character.Notes)
+		if merge.HasConflicts() {
+			conflict := &notesConflict{Theirs: character.Notes}
+
+			for _, chunk := range merge.Chunks {
+				if chunk.Conflict {
+					conflict.Chunks = append(conflict.Chunks, notesConflictChunk{
+						Index:  len(conflict.Chunks),
+						Ours:   strings.Join(chunk.Ours, ""),
+						Theirs: strings.Join(chunk.Theirs, ""),
+					})
+				}
+			}
+
+			return "", conflict, nil
+		}
+
+		base, notes = character.Notes, merge.Text()
+	}
+}
+
+// renderNotes renders notes of the character, with roll buttons when the
+// user may roll its dice and links to the characters it relates to.
+func (app *application) renderNotes(r *http.Request, character *database.Character, notes string) (template.HTML, error) {
+	related, err := app.db.GetRelatedCharacters(character.ID)
+	if err != nil {
+		return "", err
+	}
+
+	context := &markdown.Context{}
+
+	if contextGetCharacterAccess(r).Can(authz.ActionRollDice) {
+		context.RollURL = fmt.Sprintf("/character/%d/roll", character.ID)
+	}
+
+	for _, c := range related {
+		context.Characters = append(context.Characters, markdown.Character{ID: c.ID, Name: c.Name})
+	}
+
+	return app.markdown.RenderWith(notes, context), nil
+}
+
+const notesDiffContext = 2
+
+type notesRevisionView struct {
+	database.NotesRevision
+	Diff []diff.Op
+}
+
+// notesRevisionViews pairs each revision with its changes from the previous
+// one.
+func notesRevisionViews(revisions []database.NotesRevision) []notesRevisionView {
+	views := make([]notesRevisionView, len(revisions))
+
+	for i, revision := range revisions {
+		previous := ""
+		if i+1 < len(revisions) {
+			previous = revisions[i+1].Notes
+		}
+
+		views[i] = notesRevisionView{
+			NotesRevision: revision,
+			Diff:          diff.WithContext(diff.Lines(previous, revision.Notes), notesDiffContext),
+		}
+	}
+
+	return views
+}
+
+const searchResultsLength = 50
+
+type searchResultView struct {
+	ID         int
+	PlayerName string
+	Name       template.HTML
+	Snippet    template.HTML
+}
+
+// searchCharacters runs the search and keeps the characters whose notes the
+// user may view, since the snippets come from them. The database already
+// narrows the results down; the authorization policy has the final say.
+func (app *application) searchCharacters(user *database.User, input string) ([]searchResultView, error) {
+	results, err := app.db.SearchCharacters(input, user.ID, user.IsStaff || user.IsSuperuser, searchResultsLength)
+	if err != nil {
+		return nil, err
+	}
+
+	var views []searchResultView
+
+	for _, result := range results {
+		access := authz.Access{
+			IsPlayer:     result.IsPlayer,
+			IsGameMaster: result.IsGameMaster,
+			IsStaff:      user.IsStaff || user.IsSuperuser,
+		}
+
+		if !access.Can(authz.ActionViewNotes) {
+			continue
+		}
+
+		views = append(views, searchResultView{
+			ID:         result.ID,
+			PlayerName: result.PlayerName,
+			Name:       searchHighlight(result.Name),
+			Snippet:    searchHighlight(result.Snippet),
+		})
+	}
+
+	return views, nil
+}
+
+func searchHighlight(s string) template.HTML {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, database.SearchMatchStart, "<mark>")
+	s = strings.ReplaceAll(s, database.SearchMatchEnd, "</mark>")
+
+	return template.HTML(s)
+}
+
 func (app *application) backgroundTask(r *http.Request, fn func() error) {
 	app.wg.Add(1)
 
@@ -45,3 +373,1141 @@ func (app *application) backgroundTask(r *http.Request, fn func() error) {
 		}
 	}()
 }
+
+// characterDraft holds the answers of the character creation wizard. Step
+// is the furthest step reached; Rolled are the scores rolled for the roll
+// method, kept so that they cannot be rerolled.
+type characterDraft struct {
+	Step          int                   `form:"-"`
+	Name          string                `form:"Name"`
+	Gender        string                `form:"Gender"`
+	Age           int                   `form:"Age"`
+	Height        int                   `form:"Height"`
+	Weight        int                   `form:"Weight"`
+	PlayerID      int                   `form:"-"`
+	PlayerEmail   string                `form:"PlayerEmail"`
+	RaceID        int                   `form:"RaceID"`
+	ProfileID     int                   `form:"ProfileID"`
+	AbilityMethod rules.AbilityMethod   `form:"AbilityMethod"`
+	Rolled        []int                 `form:"-"`
+	Abilities     map[rules.Ability]int `form:"Abilities"`
+	Equipment     string                `form:"Equipment"`
+	MoneyPP       int                   `form:"MoneyPP"`
+	MoneyPO       int                   `form:"MoneyPO"`
+	MoneyPA       int                   `form:"MoneyPA"`
+	MoneyPC       int                   `form:"MoneyPC"`
+}
+
+const (
+	wizardStepIdentity = iota + 1
+	wizardStepRace
+	wizardStepProfile
+	wizardStepAbilities
+	wizardStepEquipment
+	wizardStepConfirm
+)
+
+var wizardSteps = []string{"Identité", "Race", "Profil", "Caractéristiques", "Équipement", "Résumé"}
+
+var genders = map[string]string{"M": "Homme", "F": "Femme", "O": "Autre"}
+
+type characterWizard struct {
+	Step      int
+	Steps     []string
+	Draft     *characterDraft
+	Races     []database.Race
+	Profiles  []database.Profile
+	Genders   map[string]string
+	Abilities []rules.Ability
+	Pool      []int
+	Budget    int
+	Character *database.Character
+	Validator validator.Validator
+}
+
+func (app *application) characterDraft(user *database.User) (*characterDraft, error) {
+	draft := &characterDraft{
+		Step:          wizardStepIdentity,
+		Gender:        "M",
+		PlayerID:      user.ID,
+		AbilityMethod: rules.MethodStandardArray,
+		Abilities:     map[rules.Ability]int{},
+	}
+
+	for i, ability := range rules.Abilities {
+		draft.Abilities[ability] = rules.StandardArray[i]
+	}
+
+	stored, err := app.db.GetCharacterDraft(user.ID)
+	if err != nil || stored == nil {
+		return draft, err
+	}
+
+	err = json.Unmarshal([]byte(stored.Data), draft)
+	if err != nil {
+		return nil, err
+	}
+
+	if draft.Abilities == nil {
+		draft.Abilities = map[rules.Ability]int{}
+	}
+
+	return draft, nil
+}
+
+func (app *application) saveCharacterDraft(user *database.User, draft *characterDraft) error {
+	data, err := json.Marshal(draft)
+	if err != nil {
+		return err
+	}
+
+	return app.db.SaveCharacterDraft(user.ID, string(data))
+}
+
+// validateCharacterDraft checks the answers of one wizard step, resolving
+// the player of characters created by staff on the way.
+func (app *application) validateCharacterDraft(user *database.User, draft *characterDraft, step int, v *validator.Validator) error {
+	switch step {
+	case wizardStepIdentity:
+		draft.Name = strings.TrimSpace(draft.Name)
+		v.CheckField(draft.Name != "", "Name", "Name is required")
+		v.CheckField(utf8.RuneCountInString(draft.Name) <= 100, "Name", "Name is too long")
+		_, ok := genders[draft.Gender]
+		v.CheckField(ok, "Gender", "Gender is invalid")
+		v.CheckField(draft.Age >= 0, "Age", "Age must not be negative")
+		v.CheckField(draft.Height >= 0, "Height", "Height must not be negative")
+		v.CheckField(draft.Weight >= 0, "Weight", "Weight must not be negative")
+
+		draft.PlayerID = user.ID
+
+		if draft.PlayerEmail != "" && (user.IsStaff || user.IsSuperuser) {
+			player, err := app.db.GetUserByEmail(draft.PlayerEmail)
+			if err != nil {
+				return err
+			}
+
+			v.CheckField(player != nil, "PlayerEmail", "No user with this email")
+
+			if player != nil {
+				draft.PlayerID = player.ID
+			}
+		}
+
+	case wizardStepRace:
+		race, err := app.db.GetRace(draft.RaceID)
+		if err != nil {
+			return err
+		}
+
+		v.CheckField(race != nil, "RaceID", "Race is required")
+
+	case wizardStepProfile:
+		profile, err := app.db.GetProfile(draft.ProfileID)
+		if err != nil {
+			return err
+		}
+
+		v.CheckField(profile != nil, "ProfileID", "Profile is required")
+
+	case wizardStepAbilities:
+		switch draft.AbilityMethod {
+		case rules.MethodStandardArray:
+			v.CheckField(rules.IsAssignment(draft.Abilities, rules.StandardArray), "Abilities", "Each value of the array must be used once")
+		case rules.MethodPointBuy:
+			cost, err := rules.PointBuyCost(draft.Abilities)
+			if err != nil {
+				v.AddFieldError("Abilities", err.Error())
+			}
+
+			v.CheckField(cost <= rules.PointBuyBudget, "Abilities", fmt.Sprintf("Point-buy costs %d points, the budget is %d", cost, rules.PointBuyBudget))
+		case rules.MethodRoll:
+			v.CheckField(rules.IsAssignment(draft.Abilities, draft.Rolled), "Abilities", "Each rolled score must be used once")
+		default:
+			v.AddFieldError("AbilityMethod", "Method is invalid")
+		}
+
+	case wizardStepEquipment:
+		v.CheckField(draft.MoneyPP >= 0, "MoneyPP", "Money must not be negative")
+		v.CheckField(draft.MoneyPO >= 0, "MoneyPO", "Money must not be negative")
+		v.CheckField(draft.MoneyPA >= 0, "MoneyPA", "Money must not be negative")
+		v.CheckField(draft.MoneyPC >= 0, "MoneyPC", "Money must not be negative")
+	}
+
+	return nil
+}
+
+// draftCharacter builds the level one character described by a complete
+// draft.
+func draftCharacter(draft *characterDraft, race *database.Race, profile *database.Profile) *database.Character {
+	character := &database.Character{
+		Name:                   draft.Name,
+		PlayerID:               draft.PlayerID,
+		RaceID:                 race.ID,
+		RaceName:               race.Name,
+		ProfileID:              profile.ID,
+		ProfileName:            profile.Name,
+		ProfileLifeDice:        profile.LifeDice,
+		ProfileMagicalStrength: profile.MagicalStrength,
+		ProfileManaMaxCompute:  profile.ManaMaxCompute,
+		Level:                  rules.StartingLevel,
+		Gender:                 draft.Gender,
+		Age:                    draft.Age,
+		Height:                 draft.Height,
+		Weight:                 draft.Weight,
+		ValueStrength:          draft.Abilities[rules.Strength],
+		ValueDexterity:         draft.Abilities[rules.Dexterity],
+		ValueConstitution:      draft.Abilities[rules.Constitution],
+		ValueIntelligence:      draft.Abilities[rules.Intelligence],
+		ValueWisdom:            draft.Abilities[rules.Wisdom],
+		ValueCharisma:          draft.Abilities[rules.Charisma],
+		Equipment:              draft.Equipment,
+		MoneyPP:                draft.MoneyPP,
+		MoneyPO:                draft.MoneyPO,
+		MoneyPA:                draft.MoneyPA,
+		MoneyPC:                draft.MoneyPC,
+	}
+
+	character.HealthMax = rules.StartingHealth(profile.LifeDice, character.ValueConstitution)
+	character.HealthRemaining = character.HealthMax
+
+	stats := rules.Compute(character)
+	character.ManaRemaining = stats.ManaMax
+	character.RecoveryPointsRemaining = stats.RecoveryPointsMax
+	character.LuckPointsRemaining = stats.LuckPointsMax
+
+	return character
+}
+
+// levelUpChoices returns the capabilities the character can take at its
+// next level.
+func (app *application) levelUpChoices(character *database.Character) ([]database.Capability, error) {
+	owned, err := app.db.GetCharacterCapabilities(character.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := app.db.GetPathCapabilities(character)
+	if err != nil {
+		return nil, err
+	}
+
+	return rules.NextCapabilities(owned, candidates), nil
+}
+
+// levelUpHitDie returns the hit die gained at the next level. A roll is kept
+// in the database until the level up is applied, so that previewing again
+// does not reroll.
+func (app *application) levelUpHitDie(character *database.Character, method rules.HealthMethod) (int, error) {
+	if method == rules.HealthAverage {
+		return rules.AverageHitDie(character.ProfileLifeDice), nil
+	}
+
+	roll, err := rules.RollHitDie(app.diceRoller, character.ProfileLifeDice)
+	if err != nil {
+		return 0, err
+	}
+
+	return app.db.KeepLevelUpRoll(character.ID, character.Level+1, roll)
+}
+
+// inventoryEntry is an item, listed after the container it is in.
+type inventoryEntry struct {
+	database.InventoryItem
+	Nested bool
+}
+
+// Container returns the id of the container the item is in, or 0.
+func (e inventoryEntry) Container() int {
+	if e.ContainerID == nil {
+		return 0
+	}
+
+	return *e.ContainerID
+}
+
+type inventoryView struct {
+	Entries           []inventoryEntry
+	Containers        []database.InventoryItem
+	Slots             []string
+	Weight            float64
+	Capacity          float64
+	Overloaded        bool
+	ImportedEquipment *string
+	// LegacyEquipment is the free-text equipment from the Django sheet, until
+	// it is imported into the inventory.
+	LegacyEquipment string
+}
+
+const moneyTransactionsLength = 10
+
+// characterInventory returns the inventory of the character.
+func (app *application) characterInventory(character *database.Character) (*inventoryView, error) {
+	imported, err := app.db.GetImportedEquipment(character.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := app.db.GetInventory(character.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &inventoryView{
+		Slots:             rules.Slots,
+		Weight:            rules.CarriedWeight(items),
+		Capacity:          rules.CarryingCapacity(character),
+		ImportedEquipment: imported,
+	}
+
+	if imported == nil {
+		view.LegacyEquipment = strings.TrimSpace(character.Equipment)
+	}
+
+	view.Overloaded = view.Weight > view.Capacity
+
+	contents := map[int][]database.InventoryItem{}
+
+	for _, item := range items {
+		if item.IsContainer {
+			view.Containers = append(view.Containers, item)
+		}
+
+		if item.ContainerID != nil {
+			contents[*item.ContainerID] = append(contents[*item.ContainerID], item)
+		}
+	}
+
+	for _, item := range items {
+		if item.ContainerID == nil {
+			view.Entries = append(view.Entries, inventoryEntry{InventoryItem: item})
+
+			for _, content := range contents[item.ID] {
+				view.Entries = append(view.Entries, inventoryEntry{InventoryItem: content, Nested: true})
+			}
+		}
+	}
+
+	return view, nil
+}
+
+type inventoryItemForm struct {
+	Name        string              `form:"Name"`
+	Quantity    int                 `form:"Quantity"`
+	Weight      float64             `form:"Weight"`
+	ContainerID int                 `form:"ContainerID"`
+	IsContainer bool                `form:"IsContainer"`
+	Equipped    bool                `form:"Equipped"`
+	Slot        string              `form:"Slot"`
+	Defense     int                 `form:"Defense"`
+	Damage      string              `form:"Damage"`
+	Validator   validator.Validator `form:"-"`
+}
+
+// inventoryItem validates the form and applies it to item. Containers hold
+// items but cannot be put in another container.
+func (app *application) inventoryItem(form *inventoryItemForm, item *database.InventoryItem) error {
+	form.Name = strings.TrimSpace(form.Name)
+
+	form.Validator.CheckField(form.Name != "", "Name", "Name is required")
+	form.Validator.CheckField(form.Quantity >= 1, "Quantity", "Quantity must be at least 1")
+	form.Validator.CheckField(form.Weight >= 0, "Weight", "Weight must not be negative")
+	form.Validator.CheckField(form.Slot == "" || slices.Contains(rules.Slots, form.Slot), "Slot", "Slot is invalid")
+	form.Validator.CheckField(form.Defense >= 0, "Defense", "Defense must not be negative")
+
+	form.Damage = strings.TrimSpace(form.Damage)
+	if form.Damage != "" {
+		_, err := rules.ParseDice(form.Damage)
+		form.Validator.CheckField(err == nil, "Damage", "Damage is not a valid dice expression")
+	}
+
+	item.ContainerID = nil
+
+	if form.ContainerID != 0 {
+		container, err := app.db.GetInventoryItem(item.CharacterID, form.ContainerID)
+		if err != nil {
+			return err
+		}
+
+		valid := container != nil && container.IsContainer && container.ContainerID == nil && container.ID != item.ID && !form.IsContainer
+		form.Validator.CheckField(valid, "ContainerID", "Container is invalid")
+
+		item.ContainerID = &form.ContainerID
+	}
+
+	item.Name = form.Name
+	item.Quantity = form.Quantity
+	item.Weight = form.Weight
+	item.IsContainer = form.IsContainer
+	item.Equipped = form.Equipped
+	item.Slot = form.Slot
+	item.Defense = form.Defense
+	item.Damage = form.Damage
+
+	return nil
+}
+
+const characterImportMaxBytes = 1_048_576
+
+// characterImport is an uploaded character resolved against the catalog of
+// this instance. Validator errors block the import, conflicts and warnings
+// are shown before it is confirmed.
+type characterImport struct {
+	Document      string
+	Version       int
+	Character     *database.Character
+	CapabilityIDs []int
+	Items         []database.InventoryItem
+	Conflicts     []string
+	Warnings      []string
+	Validator     validator.Validator
+}
+
+// resolveCharacterImport builds the character of the document for the user,
+// looking the catalog entries up by slug.
+func (app *application) resolveCharacterImport(user *database.User, document *charjson.Document, imp *characterImport) error {
+	document.Validate(&imp.Validator)
+
+	c := document.Character
+	imp.Version = document.Version
+
+	_, ok := genders[c.Gender]
+	imp.Validator.CheckField(ok, "character.gender", "Gender is invalid")
+
+	cat, err := app.catalog.Get()
+	if err != nil {
+		return err
+	}
+
+	race := cat.RaceBySlug(c.Race.Slug)
+	imp.Validator.CheckField(race != nil, "character.race.slug", fmt.Sprintf("Race %q does not exist on this instance", c.Race.Name))
+
+	profile := cat.ProfileBySlug(c.Profile.Slug)
+	imp.Validator.CheckField(profile != nil, "character.profile.slug", fmt.Sprintf("Profile %q does not exist on this instance", c.Profile.Name))
+
+	if imp.Validator.HasErrors() {
+		return nil
+	}
+
+	imp.Character = &database.Character{
+		Name:                    strings.TrimSpace(c.Name),
+		PlayerID:                user.ID,
+		PlayerName:              user.Email,
+		RaceID:                  race.ID,
+		RaceName:                race.Name,
+		ProfileID:               profile.ID,
+		ProfileName:             profile.Name,
+		ProfileLifeDice:         profile.LifeDice,
+		ProfileMagicalStrength:  profile.MagicalStrength,
+		ProfileManaMaxCompute:   profile.ManaMaxCompute,
+		Level:                   c.Level,
+		Gender:                  c.Gender,
+		Age:                     c.Age,
+		Height:                  c.Height,
+		Weight:                  c.Weight,
+		ValueStrength:           c.Abilities.Strength,
+		ValueDexterity:          c.Abilities.Dexterity,
+		ValueConstitution:       c.Abilities.Constitution,
+		ValueIntelligence:       c.Abilities.Intelligence,
+		ValueWisdom:             c.Abilities.Wisdom,
+		ValueCharisma:           c.Abilities.Charisma,
+		HealthMax:               c.HealthMax,
+		HealthRemaining:         c.Counters.Health,
+		Armor:                   c.Armor,
+		Shield:                  c.Shield,
+		DefenseMisc:             c.DefenseMisc,
+		InitiativeMisc:          c.InitiativeMisc,
+		ManaRemaining:           c.Counters.Mana,
+		RecoveryPointsRemaining: c.Counters.RecoveryPoints,
+		LuckPointsRemaining:     c.Counters.LuckPoints,
+		Equipment:               c.Equipment,
+		MoneyPP:                 c.Money.PP,
+		MoneyPO:                 c.Money.PO,
+		MoneyPA:                 c.Money.PA,
+		MoneyPC:                 c.Money.PC,
+		DamageReduction:         c.DamageReduction,
+		Notes:                   c.Notes,
+		ProfilePicture:          c.Portrait,
+	}
+
+	if manaMax := rules.Compute(imp.Character).ManaMax; imp.Character.ManaRemaining > manaMax {
+		imp.Character.ManaRemaining = manaMax
+		imp.Warnings = append(imp.Warnings, fmt.Sprintf("Le mana est ramené au maximum du profil sur cette instance (%d).", manaMax))
+	}
+
+	if c.Race.Name != race.Name || c.Profile.Name != profile.Name {
+		imp.Warnings = append(imp.Warnings, fmt.Sprintf("Race et profil importés comme %s %s.", race.Name, profile.Name))
+	}
+
+	for _, ref := range c.Capabilities {
+		capability := cat.CapabilityBySlug(ref.Path.Slug, ref.Slug)
+		if capability == nil {
+			imp.Warnings = append(imp.Warnings, fmt.Sprintf("La capacité %s (%s) n'existe pas sur cette instance et ne sera pas importée.", ref.Name, ref.Path.Name))
+			continue
+		}
+
+		if !slices.Contains(imp.CapabilityIDs, capability.ID) {
+			imp.CapabilityIDs = append(imp.CapabilityIDs, capability.ID)
+		}
+	}
+
+	for _, item := range c.Inventory {
+		imp.Items = append(imp.Items, database.InventoryItem{
+			ID:          item.ID,
+			Name:        strings.TrimSpace(item.Name),
+			Quantity:    item.Quantity,
+			Weight:      item.Weight,
+			ContainerID: item.ContainerID,
+			IsContainer: item.IsContainer,
+			Equipped:    item.Equipped,
+			Slot:        item.Slot,
+			Defense:     item.Defense,
+			Damage:      strings.TrimSpace(item.Damage),
+		})
+	}
+
+	if c.Portrait != "" {
+		imp.Warnings = append(imp.Warnings, "Le portrait n'est pas copié : seule sa référence est importée.")
+	}
+
+	exists, err := app.db.HasCharacterNamed(user.ID, imp.Character.Name)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		imp.Conflicts = append(imp.Conflicts, fmt.Sprintf("Vous avez déjà un personnage nommé %s.", imp.Character.Name))
+	}
+
+	return nil
+}
+
+const campaignInvitationTTL = 7 * 24 * time.Hour
+
+type campaignForm struct {
+	Name      string              `form:"Name"`
+	Validator validator.Validator `form:"-"`
+}
+
+type campaignInvitationForm struct {
+	Email     string              `form:"Email"`
+	Validator validator.Validator `form:"-"`
+}
+
+type campaignView struct {
+	Campaign    *database.Campaign
+	Access      authz.Access
+	Members     []database.CampaignMember
+	Characters  []database.CampaignCharacter
+	Invitations []database.CampaignInvitation
+	Available   []database.CharacterSummary
+	Encounters  []database.Encounter
+	Effects     []campaignEffectView
+	History     []effectView
+	Form        campaignInvitationForm
+	EffectForm  *effectForm
+}
+
+// campaignEffectView splits the characters of the campaign between those the
+// effect applies to and those excluded from it.
+type campaignEffectView struct {
+	effectView
+	Included []database.CampaignCharacter
+	Excluded []database.CampaignCharacter
+}
+
+// campaignView gathers the campaign page: the pending invitations for those
+// who manage it, and the characters the user could bring in.
+func (app *application) campaignView(user *database.User, campaign *database.Campaign, access authz.Access) (*campaignView, error) {
+	view := &campaignView{Campaign: campaign, Access: access}
+
+	var err error
+
+	view.Members, err = app.db.GetCampaignMembers(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	view.Characters, err = app.db.GetCampaignCharacters(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	view.Encounters, err = app.db.GetCampaignEncounters(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if access.Can(authz.ActionManageCampaign) {
+		view.Invitations, err = app.db.GetCampaignInvitations(campaign.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	effects, err := app.db.GetCampaignEffects(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions, err := app.db.GetCampaignEffectExclusions(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, effect := range effectViews(effects) {
+		ev := campaignEffectView{effectView: effect}
+
+		for _, character := range view.Characters {
+			excluded := slices.Contains(exclusions, database.CampaignEffectExclusion{CampaignEffectID: effect.ID, CharacterID: character.ID})
+			if excluded {
+				ev.Excluded = append(ev.Excluded, character)
+			} else {
+				ev.Included = append(ev.Included, character)
+			}
+		}
+
+		view.Effects = append(view.Effects, ev)
+	}
+
+	history, err := app.db.GetCampaignEffectHistory(campaign.ID, effectHistoryLength)
+	if err != nil {
+		return nil, err
+	}
+
+	view.History = effectViews(history)
+
+	if access.IsMember || access.IsGameMaster {
+		owned, err := app.db.GetPlayerCharacters(user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, character := range owned {
+			inCampaign := slices.ContainsFunc(view.Characters, func(c database.CampaignCharacter) bool { return c.ID == character.ID })
+			if !inCampaign {
+				view.Available = append(view.Available, character)
+			}
+		}
+	}
+
+	return view, nil
+}
+
+type combatantForm struct {
+	CharacterID int                 `form:"CharacterID"`
+	Name        string              `form:"Name"`
+	Initiative  int                 `form:"Initiative"`
+	Defense     int                 `form:"Defense"`
+	Reduction   int                 `form:"Reduction"`
+	Health      int                 `form:"Health"`
+	Validator   validator.Validator `form:"-"`
+}
+
+// combatantView has the current defense and health of the combatant: those
+// of the sheet for characters, those of the encounter for the others.
+type combatantView struct {
+	database.Combatant
+	Defense         int
+	DamageReduction int
+	HealthMax       int
+	HealthRemaining int
+	Effects         []database.Effect
+	Current         bool
+}
+
+type encounterView struct {
+	Campaign   *database.Campaign
+	Encounter  *database.Encounter
+	Access     authz.Access
+	Combatants []combatantView
+	Current    *combatantView
+	Available  []database.CampaignCharacter
+	Form       combatantForm
+}
+
+func (app *application) encounterView(campaign *database.Campaign, encounter *database.Encounter, access authz.Access) (*encounterView, error) {
+	view := &encounterView{Campaign: campaign, Encounter: encounter, Access: access}
+
+	combatants, err := app.db.GetCombatants(encounter.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, combatant := range combatants {
+		cv := combatantView{
+			Combatant:       combatant,
+			Defense:         combatant.Defense,
+			DamageReduction: combatant.DamageReduction,
+			HealthMax:       combatant.HealthMax,
+			HealthRemaining: combatant.HealthRemaining,
+			Current:         encounter.CurrentCombatantID != nil && *encounter.CurrentCombatantID == combatant.ID,
+		}
+
+		if combatant.CharacterID != nil {
+			character, err := app.db.GetCharacter(*combatant.CharacterID)
+			if err != nil {
+				return nil, err
+			}
+
+			if character != nil {
+				stats := rules.Compute(character)
+				cv.Defense = stats.Defense
+				cv.DamageReduction = rules.DamageReduction(character)
+				cv.HealthMax = stats.HealthMax
+				cv.HealthRemaining = character.HealthRemaining
+				cv.Effects = character.Effects
+			}
+		}
+
+		view.Combatants = append(view.Combatants, cv)
+	}
+
+	for i := range view.Combatants {
+		if view.Combatants[i].Current {
+			view.Current = &view.Combatants[i]
+		}
+	}
+
+	if access.Can(authz.ActionManageCampaign) {
+		characters, err := app.db.GetCampaignCharacters(campaign.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, character := range characters {
+			inEncounter := slices.ContainsFunc(combatants, func(c database.Combatant) bool {
+				return c.CharacterID != nil && *c.CharacterID == character.ID
+			})
+			if !inEncounter {
+				view.Available = append(view.Available, character)
+			}
+		}
+	}
+
+	return view, nil
+}
+
+// setEncounterTurn moves the encounter to the turn, counting the effects of
+// the characters down when a new round begins.
+func (app *application) setEncounterTurn(encounter *database.Encounter, round int, current *int) error {
+	moved, err := app.db.SetEncounterTurn(encounter, round, current)
+	if err != nil || !moved {
+		return err
+	}
+
+	if encounter.Round > 0 && round > encounter.Round {
+		return app.db.TickEncounterEffects(encounter.ID)
+	}
+
+	return nil
+}
+
+// nextTurn returns the turn after the current one: the next combatant in
+// initiative order, or the first one of the next round after the last one.
+func nextTurn(encounter *database.Encounter, combatants []database.Combatant) (int, *int) {
+	if len(combatants) == 0 {
+		return encounter.Round, nil
+	}
+
+	if encounter.Round == 0 {
+		return 1, &combatants[0].ID
+	}
+
+	if encounter.CurrentCombatantID == nil {
+		return encounter.Round, &combatants[0].ID
+	}
+
+	i := slices.IndexFunc(combatants, func(c database.Combatant) bool { return c.ID == *encounter.CurrentCombatantID })
+
+	switch {
+	case i < 0:
+		return encounter.Round, &combatants[0].ID
+	case i == len(combatants)-1:
+		return encounter.Round + 1, &combatants[0].ID
+	}
+
+	return encounter.Round, &combatants[i+1].ID
+}
+
+const effectHistoryLength = 10
+
+type effectForm struct {
+	Name         string                  `form:"Name"`
+	Description  string                  `form:"Description"`
+	Defense      int                     `form:"Defense"`
+	Initiative   int                     `form:"Initiative"`
+	AttackMelee  int                     `form:"AttackMelee"`
+	AttackRanged int                     `form:"AttackRanged"`
+	AttackMagic  int                     `form:"AttackMagic"`
+	DurationUnit database.EffectDuration `form:"DurationUnit"`
+	Duration     int                     `form:"Duration"`
+	Validator    validator.Validator     `form:"-"`
+}
+
+// newEffect validates the form and returns the effect it describes, timed in
+// rounds for durations in minutes too.
+func newEffect(form *effectForm, characterID, userID int) *database.Effect {
+	form.Name = strings.TrimSpace(form.Name)
+	form.Description = strings.TrimSpace(form.Description)
+
+	form.Validator.CheckField(form.Name != "", "Name", "Name is required")
+	form.Validator.CheckField(validator.MaxRunes(form.Name, 100), "Name", "Name is too long")
+	form.Validator.CheckField(validator.MaxRunes(form.Description, 1000), "Description", "Description is too long")
+
+	for _, modifier := range []int{form.Defense, form.Initiative, form.AttackMelee, form.AttackRanged, form.AttackMagic} {
+		form.Validator.CheckField(validator.Between(modifier, -20, 20), "Modifiers", "Modifiers must be between -20 and 20")
+	}
+
+	form.Validator.CheckField(validator.In(form.DurationUnit, database.EffectDurations...), "Duration", "Unknown duration")
+
+	effect := &database.Effect{
+		CharacterID:  characterID,
+		Name:         form.Name,
+		Description:  form.Description,
+		Defense:      form.Defense,
+		Initiative:   form.Initiative,
+		AttackMelee:  form.AttackMelee,
+		AttackRanged: form.AttackRanged,
+		AttackMagic:  form.AttackMagic,
+		DurationUnit: form.DurationUnit,
+		CreatedByID:  userID,
+	}
+
+	switch form.DurationUnit {
+	case database.EffectRounds, database.EffectMinutes:
+		form.Validator.CheckField(validator.Between(form.Duration, 1, 1000), "Duration", "Duration must be between 1 and 1000")
+
+		rounds := form.Duration
+		if form.DurationUnit == database.EffectMinutes {
+			rounds *= rules.RoundsPerMinute
+		}
+
+		effect.Duration = form.Duration
+		effect.RoundsRemaining = &rounds
+	}
+
+	return effect
+}
+
+type effectView struct {
+	database.Effect
+	Modifiers []string
+	Remaining string
+}
+
+func effectViews(effects []database.Effect) []effectView {
+	views := make([]effectView, 0, len(effects))
+
+	for _, effect := range effects {
+		view := effectView{Effect: effect}
+
+		modifiers := []struct {
+			label string
+			value int
+		}{
+			{"DEF", effect.Defense},
+			{"Init.", effect.Initiative},
+			{"Contact", effect.AttackMelee},
+			{"Distance", effect.AttackRanged},
+			{"Magie", effect.AttackMagic},
+		}
+
+		for _, modifier := range modifiers {
+			if modifier.value != 0 {
+				view.Modifiers = append(view.Modifiers, fmt.Sprintf("%s %+d", modifier.label, modifier.value))
+			}
+		}
+
+		switch {
+		case effect.RoundsRemaining == nil && effect.DurationUnit == database.EffectUntilRest:
+			view.Remaining = "jusqu'au prochain repos"
+		case effect.RoundsRemaining == nil:
+			view.Remaining = "jusqu'à son retrait"
+		case effect.DurationUnit == database.EffectMinutes:
+			view.Remaining = fmt.Sprintf("encore %d min", (*effect.RoundsRemaining+rules.RoundsPerMinute-1)/rules.RoundsPerMinute)
+		default:
+			view.Remaining = fmt.Sprintf("encore %d round(s)", *effect.RoundsRemaining)
+		}
+
+		views = append(views, view)
+	}
+
+	return views
+}
+
+const restUndoWindow = 10 * time.Minute
+
+type restForm struct {
+	Kind      rules.RestKind      `form:"Kind"`
+	Spend     int                 `form:"Spend"`
+	Validator validator.Validator `form:"-"`
+}
+
+type restView struct {
+	*database.Rest
+	CanUndo bool
+}
+
+func newRestView(rest *database.Rest) *restView {
+	return &restView{
+		Rest:    rest,
+		CanUndo: rest.Undone == nil && time.Since(rest.Created) < restUndoWindow,
+	}
+}
+
+// planRest rolls the rest of the character, listing the effects it ends.
+func (app *application) planRest(character *database.Character, kind rules.RestKind, spend int) (database.RestCharacter, error) {
+	rest, err := rules.PlanRest(app.diceRoller, character, kind, spend)
+	if err != nil {
+		return database.RestCharacter{}, err
+	}
+
+	stats := rules.Compute(character)
+
+	var rolls, effects []string
+
+	for _, roll := range rest.Rolls {
+		rolls = append(rolls, strconv.Itoa(roll))
+	}
+
+	for _, effect := range character.Effects {
+		if effect.DurationUnit == database.EffectUntilRest {
+			effects = append(effects, effect.Name)
+		}
+	}
+
+	return database.RestCharacter{
+		CharacterID:    character.ID,
+		CharacterName:  character.Name,
+		HealthBefore:   character.HealthRemaining,
+		HealthAfter:    character.HealthRemaining + rest.HealthGain,
+		ManaBefore:     character.ManaRemaining,
+		ManaAfter:      character.ManaRemaining + rest.ManaGain,
+		RecoveryBefore: character.RecoveryPointsRemaining,
+		RecoveryAfter:  character.RecoveryPointsRemaining - rest.RecoverySpent + rest.RecoveryGained,
+		Rolls:          strings.Join(rolls, ", "),
+		Effects:        strings.Join(effects, ", "),
+		HealthMax:      stats.HealthMax,
+		ManaMax:        stats.ManaMax,
+		RecoveryMax:    stats.RecoveryPointsMax,
+	}, nil
+}
+
+func validateRestForm(form *restForm) {
+	form.Validator.CheckField(validator.In(form.Kind, rules.RestKinds...), "Kind", "Unknown rest")
+	form.Validator.CheckField(validator.Between(form.Spend, 0, rules.RecoveryPointsMax), "Spend", fmt.Sprintf("Must be between 0 and %d", rules.RecoveryPointsMax))
+}
+
+type attackForm struct {
+	Weapon    string              `form:"Weapon"`
+	Attack    rules.AttackKind    `form:"Attack"`
+	Damage    string              `form:"Damage"`
+	Critical  int                 `form:"Critical"`
+	Target    string              `form:"Target"`
+	Apply     bool                `form:"Apply"`
+	Validator validator.Validator `form:"-"`
+}
+
+// attackWeapon is a weapon of the inventory or a capability, whose value in
+// the form is "item:<id>" or "capability:<id>". Capabilities have no attack
+// nor damage of their own, those of the form are used.
+type attackWeapon struct {
+	Value  string
+	Name   string
+	Attack rules.AttackKind
+	Damage string
+}
+
+// attackTarget is a character of the campaign, "character:<id>", or a
+// non-player character of an ongoing encounter, "combatant:<id>".
+type attackTarget struct {
+	Value     string
+	Name      string
+	Defense   int
+	Reduction int
+	Character *database.Character
+	Combatant *database.Combatant
+}
+
+type attackView struct {
+	CampaignID *int
+	Items      []attackWeapon
+	Spells     []attackWeapon
+	Characters []attackTarget
+	Combatants []attackTarget
+}
+
+func (v *attackView) weapon(value string) (attackWeapon, bool) {
+	weapons := append(slices.Clip(v.Items), v.Spells...)
+
+	i := slices.IndexFunc(weapons, func(w attackWeapon) bool { return w.Value == value })
+	if i < 0 {
+		return attackWeapon{}, false
+	}
+
+	return weapons[i], true
+}
+
+func (v *attackView) target(value string) (attackTarget, bool) {
+	targets := append(slices.Clip(v.Characters), v.Combatants...)
+
+	i := slices.IndexFunc(targets, func(t attackTarget) bool { return t.Value == value })
+	if i < 0 {
+		return attackTarget{}, false
+	}
+
+	return targets[i], true
+}
+
+// attackView lists what the character attacks with, and what it can attack:
+// the other characters of its campaign and the non-player characters of the
+// ongoing encounters of the campaign.
+func (app *application) attackView(character *database.Character) (*attackView, error) {
+	view := &attackView{}
+
+	items, err := app.db.GetInventory(character.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		switch item.Slot {
+		case rules.SlotMelee:
+			view.Items = append(view.Items, attackWeapon{Value: fmt.Sprintf("item:%d", item.ID), Name: item.Name, Attack: rules.AttackMelee, Damage: item.Damage})
+		case rules.SlotRanged:
+			view.Items = append(view.Items, attackWeapon{Value: fmt.Sprintf("item:%d", item.ID), Name: item.Name, Attack: rules.AttackRanged, Damage: item.Damage})
+		}
+	}
+
+	capabilities, err := app.db.GetCharacterCapabilities(character.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, capability := range capabilities {
+		view.Spells = append(view.Spells, attackWeapon{Value: fmt.Sprintf("capability:%d", capability.ID), Name: capability.Name})
+	}
+
+	view.CampaignID, err = app.db.GetCharacterCampaignID(character.ID)
+	if err != nil || view.CampaignID == nil {
+		return view, err
+	}
+
+	characters, err := app.db.GetCampaignCharacters(*view.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cc := range characters {
+		if cc.ID == character.ID {
+			continue
+		}
+
+		target, err := app.db.GetCharacter(cc.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if target == nil {
+			continue
+		}
+
+		view.Characters = append(view.Characters, attackTarget{
+			Value:     fmt.Sprintf("character:%d", target.ID),
+			Name:      target.Name,
+			Defense:   rules.Compute(target).Defense,
+			Reduction: rules.DamageReduction(target),
+			Character: target,
+		})
+	}
+
+	encounters, err := app.db.GetCampaignEncounters(*view.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, encounter := range encounters {
+		if encounter.Ended != nil {
+			continue
+		}
+
+		combatants, err := app.db.GetCombatants(encounter.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range combatants {
+			combatant := &combatants[i]
+			if combatant.CharacterID != nil {
+				continue
+			}
+
+			view.Combatants = append(view.Combatants, attackTarget{
+				Value:     fmt.Sprintf("combatant:%d", combatant.ID),
+				Name:      fmt.Sprintf("%s (%s)", combatant.Name, encounter.Name),
+				Defense:   combatant.Defense,
+				Reduction: combatant.DamageReduction,
+				Combatant: combatant,
+			})
+		}
+	}
+
+	return view, nil
+}
+
+// recordAttack stores the attack in the shared roll history of the
+// campaign, its total being the damage dealt.
+func (app *application) recordAttack(user *database.User, character *database.Character, campaignID *int, attack *rules.Attack, summary string) error {
+	detail, err := json.Marshal(attack)
+	if err != nil {
+		return err
+	}
+
+	_, err = app.db.InsertDiceRoll(&database.DiceRoll{
+		CharacterID: character.ID,
+		CampaignID:  campaignID,
+		UserID:      user.ID,
+		Expression:  attack.Roll.Expression,
+		Total:       attack.Dealt,
+		Breakdown:   summary,
+		Detail:      string(detail),
+	})
+
+	return err
+}
+
+// attackSummary describes the attack for the roll history, e.g. "Épée
+// longue contre Loup : 1d20+5 [14] + 5 = 19 contre DEF 12, touché, 1d8 [6] =
+// 6 - RD 2 = 4 dégâts".
+func attackSummary(weapon, target string, attack *rules.Attack) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s contre %s : %s contre DEF %d, ", weapon, target, attack.Roll.String(), attack.Defense)
+
+	switch {
+	case attack.Critical:
+		sb.WriteString("critique")
+	case attack.Hit:
+		sb.WriteString("touché")
+	case attack.Fumble:
+		sb.WriteString("échec critique")
+	default:
+		sb.WriteString("raté")
+	}
+
+	if attack.Damage != nil {
+		fmt.Fprintf(&sb, ", %s", attack.Damage.String())
+
+		if attack.Critical {
+			sb.WriteString(" × 2")
+		}
+
+		if attack.Reduction > 0 {
+			fmt.Fprintf(&sb, " - RD %d", attack.Reduction)
+		}
+
+		fmt.Fprintf(&sb, " = %d dégâts", attack.Dealt)
+	}
+
+	return sb.String()
+}
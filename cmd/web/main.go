@@ -7,9 +7,15 @@ import (
 	"net/http"
 	"os"
 	"runtime/debug"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/Crocmagnon/charasheet-go/internal/catalog"
 	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+	"github.com/Crocmagnon/charasheet-go/internal/django"
+	"github.com/Crocmagnon/charasheet-go/internal/markdown"
 	"github.com/Crocmagnon/charasheet-go/internal/smtp"
 	"github.com/Crocmagnon/charasheet-go/internal/version"
 	"github.com/gorilla/sessions"
@@ -33,10 +39,24 @@ type config struct {
 	cookie     struct {
 		secretKey string
 	}
+	catalog struct {
+		cacheTTL time.Duration
+	}
 	db struct {
 		dsn         string
 		automigrate bool
 	}
+	django struct {
+		secretKey          string
+		secretKeyFallbacks []string
+	}
+	markdown struct {
+		externalLinkRel    string
+		externalLinkTarget string
+	}
+	notes struct {
+		revisionsMax int
+	}
 	notifications struct {
 		email string
 	}
@@ -54,12 +74,16 @@ type config struct {
 }
 
 type application struct {
-	config       config
-	db           *database.DB
-	logger       *slog.Logger
-	mailer       *smtp.Mailer
-	sessionStore *sessions.CookieStore
-	wg           sync.WaitGroup
+	config              config
+	catalog             *catalog.Cache
+	db                  *database.DB
+	djangoSessionSigner *django.Signer
+	diceRoller          *dice.Roller
+	logger              *slog.Logger
+	mailer              *smtp.Mailer
+	markdown            *markdown.Renderer
+	sessionStore        *sessions.CookieStore
+	wg                  sync.WaitGroup
 }
 
 func run(logger *slog.Logger) error {
@@ -68,8 +92,17 @@ func run(logger *slog.Logger) error {
 	flag.StringVar(&cfg.baseURL, "base-url", "http://localhost:4444", "base URL for the application")
 	flag.StringVar(&cfg.listenAddr, "http-listen-addr", "127.0.0.1:4444", "addr to listen on for HTTP requests")
 	flag.StringVar(&cfg.cookie.secretKey, "cookie-secret-key", "wz7t47hz37xtl36xiebp2wfehmaoiunt", "secret key for cookie authentication/encryption")
+	flag.DurationVar(&cfg.catalog.cacheTTL, "catalog-cache-ttl", 10*time.Minute, "how long the rules catalog is cached before reading it again")
 	flag.StringVar(&cfg.db.dsn, "db-dsn", "db.sqlite", "sqlite3 DSN")
 	flag.BoolVar(&cfg.db.automigrate, "db-automigrate", true, "run migrations on startup")
+	flag.StringVar(&cfg.django.secretKey, "django-secret-key", "", "Django SECRET_KEY, used to verify Django sessions")
+	flag.Func("django-secret-key-fallbacks", "comma-separated Django SECRET_KEY_FALLBACKS", func(s string) error {
+		cfg.django.secretKeyFallbacks = strings.Split(s, ",")
+		return nil
+	})
+	flag.StringVar(&cfg.markdown.externalLinkRel, "markdown-external-link-rel", "noopener noreferrer", "rel attribute added to external links in user content")
+	flag.StringVar(&cfg.markdown.externalLinkTarget, "markdown-external-link-target", "_blank", "target attribute added to external links in user content")
+	flag.IntVar(&cfg.notes.revisionsMax, "notes-revisions-max", 100, "number of notes revisions kept per character")
 	flag.StringVar(&cfg.notifications.email, "notifications-email", "", "contact email address for error notifications")
 	flag.StringVar(&cfg.session.secretKey, "session-secret-key", "2amoy2vtykegaujn3cc5g3woub7tv5g6", "secret key for session cookie authentication")
 	flag.StringVar(&cfg.session.oldSecretKey, "session-old-secret-key", "", "previous secret key for session cookie authentication")
@@ -110,12 +143,32 @@ func run(logger *slog.Logger) error {
 		Secure:   true,
 	}
 
+	diceRoller, err := dice.NewCryptoSeededRoller()
+	if err != nil {
+		return err
+	}
+
+	var djangoSessionSigner *django.Signer
+	if cfg.django.secretKey != "" {
+		djangoSessionSigner = django.NewSigner(cfg.django.secretKey, cfg.django.secretKeyFallbacks, django.SessionSalt)
+	}
+
+	markdownPolicy := markdown.DefaultPolicy()
+	markdownPolicy.ExternalLinkRel = cfg.markdown.externalLinkRel
+	markdownPolicy.ExternalLinkTarget = cfg.markdown.externalLinkTarget
+
+	markdownRenderer := markdown.NewRenderer(markdownPolicy)
+
 	app := &application{
-		config:       cfg,
-		db:           db,
-		logger:       logger,
-		mailer:       mailer,
-		sessionStore: sessionStore,
+		config:              cfg,
+		catalog:             catalog.NewCache(db, markdownRenderer, cfg.catalog.cacheTTL),
+		db:                  db,
+		djangoSessionSigner: djangoSessionSigner,
+		diceRoller:          diceRoller,
+		logger:              logger,
+		mailer:              mailer,
+		markdown:            markdownRenderer,
+		sessionStore:        sessionStore,
 	}
 
 	return app.serveHTTP()
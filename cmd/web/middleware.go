@@ -1,12 +1,15 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 
+	"github.com/Crocmagnon/charasheet-go/internal/authz"
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/julienschmidt/httprouter"
+	"github.com/justinas/alice"
 	"github.com/justinas/nosurf"
 )
 
@@ -55,88 +58,165 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		var user *database.User
+
 		userID, ok := session.Values["userID"].(int)
-		if !ok {
-			userID, err = app.getUserIDFromDjangoSession(r)
+		if ok {
+			user, err = app.db.GetUser(userID)
+		} else {
+			user, err = app.getUserFromDjangoSession(r)
+		}
+
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if user != nil {
+			r = contextSetAuthenticatedUser(r, user)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) requireAuthenticatedUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticatedUser := contextGetAuthenticatedUser(r)
+
+		if authenticatedUser == nil {
+			session, err := app.sessionStore.Get(r, "session")
 			if err != nil {
 				app.serverError(w, r, err)
 				return
 			}
 
-			ok = userID > 0
-		}
+			session.Values["redirectPathAfterLogin"] = r.URL.Path
 
-		if ok {
-			user, err := app.db.GetUser(userID)
+			err = session.Save(r, w)
 			if err != nil {
 				app.serverError(w, r, err)
 				return
 			}
 
-			if user != nil {
-				r = contextSetAuthenticatedUser(r, user)
-			}
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
 		}
 
+		w.Header().Add("Cache-Control", "no-store")
+
 		next.ServeHTTP(w, r)
 	})
 }
 
-func (app *application) getUserIDFromDjangoSession(r *http.Request) (int, error) {
-	sessionIDCookie, err := r.Cookie("sessionid")
+func (app *application) requireCharacterPermission(action authz.Action) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+			if err != nil || id < 1 {
+				app.notFound(w, r)
+				return
+			}
 
-	switch {
-	case errors.Is(err, http.ErrNoCookie):
-		return 0, nil
-	case err != nil:
-		return 0, fmt.Errorf("getting cookie 'sessionid': %w", err)
-	}
+			character, err := app.db.GetCharacter(id)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
 
-	session, err := app.db.GetSession(sessionIDCookie.Value)
-	if err != nil {
-		return 0, fmt.Errorf("getting session from db: %w", err)
-	}
+			if character == nil {
+				app.notFound(w, r)
+				return
+			}
 
-	sessionData, err := session.Decode()
-	if err != nil {
-		return 0, fmt.Errorf("decoding session: %w", err)
-	}
+			access, err := app.characterAccess(contextGetAuthenticatedUser(r), character)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
 
-	userID, err := strconv.Atoi(sessionData.AuthUserID)
-	if err != nil {
-		return 0, fmt.Errorf("converting userID to int: %w", err)
-	}
+			if !access.Can(action) {
+				app.forbidden(w, r)
+				return
+			}
+
+			r = contextSetCharacter(r, character, access)
 
-	return userID, nil
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
-func (app *application) requireAuthenticatedUser(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authenticatedUser := contextGetAuthenticatedUser(r)
+func (app *application) requireCampaignPermission(action authz.Action) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+			if err != nil || id < 1 {
+				app.notFound(w, r)
+				return
+			}
 
-		if authenticatedUser == nil {
-			session, err := app.sessionStore.Get(r, "session")
+			campaign, err := app.db.GetCampaign(id)
 			if err != nil {
 				app.serverError(w, r, err)
 				return
 			}
 
-			session.Values["redirectPathAfterLogin"] = r.URL.Path
+			if campaign == nil {
+				app.notFound(w, r)
+				return
+			}
 
-			err = session.Save(r, w)
+			access, err := app.campaignAccess(contextGetAuthenticatedUser(r), campaign)
 			if err != nil {
 				app.serverError(w, r, err)
 				return
 			}
 
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
-			return
-		}
+			if !access.Can(action) {
+				app.forbidden(w, r)
+				return
+			}
 
-		w.Header().Add("Cache-Control", "no-store")
+			r = contextSetCampaign(r, campaign, access)
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireEncounter loads an encounter of the campaign loaded by
+// requireCampaignPermission. With ongoing, ended encounters are refused.
+func (app *application) requireEncounter(ongoing bool) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("encounter"))
+			if err != nil || id < 1 {
+				app.notFound(w, r)
+				return
+			}
+
+			encounter, err := app.db.GetEncounter(id)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			if encounter == nil || encounter.CampaignID != contextGetCampaign(r).ID {
+				app.notFound(w, r)
+				return
+			}
+
+			if ongoing && encounter.Ended != nil {
+				app.errorPage(w, r, http.StatusConflict, "This encounter has ended")
+				return
+			}
+
+			r = contextSetEncounter(r, encounter)
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 func (app *application) requireAnonymousUser(next http.Handler) http.Handler {
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/Crocmagnon/charasheet-go/assets"
+	"github.com/Crocmagnon/charasheet-go/internal/authz"
+	"github.com/Crocmagnon/charasheet-go/internal/catalog"
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
 )
@@ -22,20 +24,112 @@ func (app *application) routes() http.Handler {
 	appMiddleware = appMiddleware.Append(app.authenticate)
 	mux.Handler("GET", "/", appMiddleware.ThenFunc(app.home))
 
-	// mux.Handler("GET", "/login", anonymous.ThenFunc(app.login))
-
-	//mux.Handler("GET", "/forgotten-password", anonymous.ThenFunc(app.forgottenPassword))
-	//mux.Handler("POST", "/forgotten-password", anonymous.ThenFunc(app.forgottenPassword))
-	//mux.Handler("GET", "/forgotten-password-confirmation", anonymous.ThenFunc(app.forgottenPasswordConfirmation))
-	//mux.Handler("GET", "/password-reset/:plaintextToken", anonymous.ThenFunc(app.passwordReset))
-	//mux.Handler("POST", "/password-reset/:plaintextToken", anonymous.ThenFunc(app.passwordReset))
-	//mux.Handler("GET", "/password-reset-confirmation", anonymous.ThenFunc(app.passwordResetConfirmation))
+	anonymous := appMiddleware.Append(app.requireAnonymousUser)
+	mux.Handler("GET", "/signup", anonymous.ThenFunc(app.signup))
+	mux.Handler("POST", "/signup", anonymous.ThenFunc(app.signup))
+	mux.Handler("GET", "/login", anonymous.ThenFunc(app.login))
+	mux.Handler("POST", "/login", anonymous.ThenFunc(app.login))
+	mux.Handler("GET", "/forgotten-password", anonymous.ThenFunc(app.forgottenPassword))
+	mux.Handler("POST", "/forgotten-password", anonymous.ThenFunc(app.forgottenPassword))
+	mux.Handler("GET", "/forgotten-password-confirmation", anonymous.ThenFunc(app.forgottenPasswordConfirmation))
+	mux.Handler("GET", "/password-reset/:plaintextToken", anonymous.ThenFunc(app.passwordReset))
+	mux.Handler("POST", "/password-reset/:plaintextToken", anonymous.ThenFunc(app.passwordReset))
+	mux.Handler("GET", "/password-reset-confirmation", anonymous.ThenFunc(app.passwordResetConfirmation))
 
 	authenticated := appMiddleware.Append(app.requireAuthenticatedUser)
 	mux.Handler("POST", "/logout", authenticated.ThenFunc(app.logout))
+	mux.Handler("GET", "/search", authenticated.ThenFunc(app.search))
+	mux.Handler("GET", "/catalog", authenticated.ThenFunc(app.catalogIndex))
+	mux.Handler("GET", "/catalog/paths", authenticated.ThenFunc(app.catalogPaths))
+	mux.Handler("GET", "/catalog/paths/:id", authenticated.ThenFunc(app.catalogPath))
+	mux.Handler("GET", "/catalog/capabilities", authenticated.ThenFunc(app.catalogCapabilities))
+	mux.Handler("GET", "/api/catalog/races", authenticated.ThenFunc(app.catalogJSON(func(c *catalog.Catalog, _ catalog.Filter) any { return c.Races })))
+	mux.Handler("GET", "/api/catalog/profiles", authenticated.ThenFunc(app.catalogJSON(func(c *catalog.Catalog, _ catalog.Filter) any { return c.Profiles })))
+	mux.Handler("GET", "/api/catalog/paths", authenticated.ThenFunc(app.catalogJSON(func(c *catalog.Catalog, f catalog.Filter) any { return c.FilterPaths(f) })))
+	mux.Handler("GET", "/api/catalog/paths/:id", authenticated.ThenFunc(app.catalogPathJSON))
+	mux.Handler("GET", "/api/catalog/capabilities", authenticated.ThenFunc(app.catalogJSON(func(c *catalog.Catalog, f catalog.Filter) any { return c.FilterCapabilities(f) })))
+	mux.Handler("GET", "/characters/new", authenticated.ThenFunc(app.characterCreate))
+	mux.Handler("POST", "/characters/new", authenticated.ThenFunc(app.characterCreate))
+	mux.Handler("POST", "/characters/new/cancel", authenticated.ThenFunc(app.characterCreateCancel))
+	mux.Handler("GET", "/characters/import", authenticated.ThenFunc(app.characterImport))
+	mux.Handler("POST", "/characters/import", authenticated.ThenFunc(app.characterImport))
+	mux.Handler("GET", "/campaigns", authenticated.ThenFunc(app.campaigns))
+	mux.Handler("POST", "/campaigns", authenticated.ThenFunc(app.campaigns))
+	mux.Handler("GET", "/invitations/:plaintextToken", authenticated.ThenFunc(app.campaignInvitation))
+	mux.Handler("POST", "/invitations/:plaintextToken/accept", authenticated.ThenFunc(app.campaignInvitationAccept))
+	mux.Handler("POST", "/invitations/:plaintextToken/decline", authenticated.ThenFunc(app.campaignInvitationDecline))
+
+	viewCampaign := authenticated.Append(app.requireCampaignPermission(authz.ActionViewCampaign))
+	mux.Handler("GET", "/campaigns/:id", viewCampaign.ThenFunc(app.campaignDetail))
+	mux.Handler("POST", "/campaigns/:id/characters", viewCampaign.ThenFunc(app.campaignCharacterAdd))
+	mux.Handler("POST", "/campaigns/:id/characters/:character/delete", viewCampaign.ThenFunc(app.campaignCharacterRemove))
+
+	manageCampaign := authenticated.Append(app.requireCampaignPermission(authz.ActionManageCampaign))
+	mux.Handler("POST", "/campaigns/:id/invitations", manageCampaign.ThenFunc(app.campaignInvite))
+	mux.Handler("POST", "/campaigns/:id/members/:user/delete", manageCampaign.ThenFunc(app.campaignMemberRemove))
+	mux.Handler("POST", "/campaigns/:id/encounters", manageCampaign.ThenFunc(app.campaignEncounterCreate))
+	mux.Handler("POST", "/campaigns/:id/effects", manageCampaign.ThenFunc(app.campaignEffectAdd))
+	mux.Handler("POST", "/campaigns/:id/effects/:effect/end", manageCampaign.ThenFunc(app.campaignEffectEnd))
+	mux.Handler("POST", "/campaigns/:id/effects/:effect/exclusions", manageCampaign.ThenFunc(app.campaignEffectExclude))
+	mux.Handler("POST", "/campaigns/:id/rest", manageCampaign.ThenFunc(app.campaignRest))
+	mux.Handler("POST", "/campaigns/:id/rest/:rest/undo", manageCampaign.ThenFunc(app.campaignRestUndo))
+
+	viewEncounter := viewCampaign.Append(app.requireEncounter(false))
+	mux.Handler("GET", "/campaigns/:id/encounters/:encounter", viewEncounter.ThenFunc(app.encounterDetail))
+	mux.Handler("GET", "/campaigns/:id/encounters/:encounter/tracker", viewEncounter.ThenFunc(app.encounterTracker))
+
+	manageEncounter := manageCampaign.Append(app.requireEncounter(true))
+	mux.Handler("POST", "/campaigns/:id/encounters/:encounter/combatants", manageEncounter.ThenFunc(app.encounterCombatantAdd))
+	mux.Handler("POST", "/campaigns/:id/encounters/:encounter/combatants/:combatant/delete", manageEncounter.ThenFunc(app.encounterCombatantRemove))
+	mux.Handler("POST", "/campaigns/:id/encounters/:encounter/combatants/:combatant/health", manageEncounter.ThenFunc(app.encounterCombatantHealth))
+	mux.Handler("POST", "/campaigns/:id/encounters/:encounter/initiative", manageEncounter.ThenFunc(app.encounterInitiative))
+	mux.Handler("POST", "/campaigns/:id/encounters/:encounter/next", manageEncounter.ThenFunc(app.encounterNextTurn))
+	mux.Handler("POST", "/campaigns/:id/encounters/:encounter/end", manageEncounter.ThenFunc(app.encounterEnd))
+
+	viewCharacter := authenticated.Append(app.requireCharacterPermission(authz.ActionView))
+	mux.Handler("GET", "/character/:id", viewCharacter.ThenFunc(app.characterDetail))
+	mux.Handler("GET", "/character/:id/stats", viewCharacter.ThenFunc(app.characterStats))
+	mux.Handler("GET", "/character/:id/sheet.pdf", viewCharacter.ThenFunc(app.characterSheetPDF))
+
+	viewNotes := authenticated.Append(app.requireCharacterPermission(authz.ActionViewNotes))
+	mux.Handler("GET", "/character/:id/export.json", viewNotes.ThenFunc(app.characterExport))
+	mux.Handler("GET", "/character/:id/notes", viewNotes.ThenFunc(app.characterNotes))
+	mux.Handler("GET", "/character/:id/notes_history/", viewNotes.ThenFunc(app.characterNotesHistory))
+
+	editNotes := authenticated.Append(app.requireCharacterPermission(authz.ActionEditNotes))
+	mux.Handler("GET", "/character/:id/notes_change/", editNotes.ThenFunc(app.characterNotesChange))
+	mux.Handler("POST", "/character/:id/notes_change/", editNotes.ThenFunc(app.characterNotesChange))
+	mux.Handler("POST", "/character/:id/notes_history/:revision/restore", editNotes.ThenFunc(app.characterNotesRestore))
+
+	editCounters := authenticated.Append(app.requireCharacterPermission(authz.ActionEditCounters))
+	for counter, slug := range counterSlugs {
+		mux.Handler("POST", "/character/:id/"+slug+"_change/", editCounters.Then(app.characterCounterChange(counter)))
+	}
+	mux.Handler("POST", "/character/:id/rest", editCounters.ThenFunc(app.characterRest))
+	mux.Handler("POST", "/character/:id/rest/:rest/undo", editCounters.ThenFunc(app.characterRestUndo))
+
+	mux.Handler("GET", "/character/:id/rolls", viewCharacter.ThenFunc(app.characterRollHistory))
+
+	rollDice := authenticated.Append(app.requireCharacterPermission(authz.ActionRollDice))
+	mux.Handler("POST", "/character/:id/roll", rollDice.ThenFunc(app.characterRoll))
+	mux.Handler("POST", "/character/:id/roll.json", rollDice.ThenFunc(app.characterRollJSON))
+	mux.Handler("POST", "/character/:id/attack", rollDice.ThenFunc(app.characterAttack))
+
+	levelUp := authenticated.Append(app.requireCharacterPermission(authz.ActionLevelUp))
+	mux.Handler("GET", "/character/:id/level_up", levelUp.ThenFunc(app.characterLevelUp))
+	mux.Handler("POST", "/character/:id/level_up/preview", levelUp.ThenFunc(app.characterLevelUpPreview))
+	mux.Handler("POST", "/character/:id/level_up", levelUp.ThenFunc(app.characterLevelUpApply))
+
+	editItems := authenticated.Append(app.requireCharacterPermission(authz.ActionEditItems))
+	mux.Handler("POST", "/character/:id/inventory", editItems.ThenFunc(app.characterInventoryAdd))
+	mux.Handler("POST", "/character/:id/inventory_import", editItems.ThenFunc(app.characterInventoryImport))
+	mux.Handler("POST", "/character/:id/inventory/:item", editItems.ThenFunc(app.characterInventoryUpdate))
+	mux.Handler("POST", "/character/:id/inventory/:item/delete", editItems.ThenFunc(app.characterInventoryDelete))
+	mux.Handler("POST", "/character/:id/money", editItems.ThenFunc(app.characterMoney))
 
-	mux.Handler("GET", "/character/:id/notes_change/", authenticated.ThenFunc(app.characterNotesChange))
-	mux.Handler("POST", "/character/:id/notes_change/", authenticated.ThenFunc(app.characterNotesChange))
+	editEffects := authenticated.Append(app.requireCharacterPermission(authz.ActionEditEffects))
+	mux.Handler("POST", "/character/:id/effects", editEffects.ThenFunc(app.characterEffectAdd))
+	mux.Handler("POST", "/character/:id/effects/:effect/delete", editEffects.ThenFunc(app.characterEffectRemove))
 
 	defaultMiddleware := alice.New(app.logging, app.recoverPanic, app.securityHeaders)
 	return defaultMiddleware.Then(mux)
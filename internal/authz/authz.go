@@ -0,0 +1,76 @@
+package authz
+
+type Role int
+
+const (
+	RolePlayer Role = iota
+	RoleGameMaster
+	RoleStaff
+	RoleMember
+)
+
+type Action string
+
+const (
+	ActionView         Action = "view"
+	ActionViewNotes    Action = "view_notes"
+	ActionEditNotes    Action = "edit_notes"
+	ActionEditCounters Action = "edit_counters"
+	ActionRollDice     Action = "roll_dice"
+	ActionLevelUp      Action = "level_up"
+	ActionEditItems    Action = "edit_items"
+	ActionEditEffects  Action = "edit_effects"
+
+	ActionViewCampaign   Action = "view_campaign"
+	ActionManageCampaign Action = "manage_campaign"
+)
+
+var policies = map[Action][]Role{
+	ActionView:         {RolePlayer, RoleGameMaster, RoleStaff, RoleMember},
+	ActionViewNotes:    {RolePlayer, RoleGameMaster, RoleStaff},
+	ActionEditNotes:    {RolePlayer, RoleGameMaster, RoleStaff},
+	ActionEditCounters: {RolePlayer, RoleGameMaster, RoleStaff},
+	ActionRollDice:     {RolePlayer, RoleGameMaster, RoleStaff},
+	ActionLevelUp:      {RolePlayer, RoleGameMaster, RoleStaff},
+	ActionEditItems:    {RolePlayer, RoleGameMaster, RoleStaff},
+	ActionEditEffects:  {RolePlayer, RoleGameMaster, RoleStaff},
+
+	ActionViewCampaign:   {RoleGameMaster, RoleStaff, RoleMember},
+	ActionManageCampaign: {RoleGameMaster, RoleStaff},
+}
+
+// Access describes how a user relates to a character or a campaign. A user
+// can hold several roles at once, e.g. the game master playing their own
+// character. Members are the players of a campaign: for a character, the
+// members of a campaign it plays in.
+type Access struct {
+	IsPlayer     bool
+	IsGameMaster bool
+	IsStaff      bool
+	IsMember     bool
+}
+
+func (a Access) Has(role Role) bool {
+	switch role {
+	case RolePlayer:
+		return a.IsPlayer
+	case RoleGameMaster:
+		return a.IsGameMaster
+	case RoleStaff:
+		return a.IsStaff
+	case RoleMember:
+		return a.IsMember
+	}
+
+	return false
+}
+
+func (a Access) Can(action Action) bool {
+	for _, role := range policies[action] {
+		if a.Has(role) {
+			return true
+		}
+	}
+
+	return false
+}
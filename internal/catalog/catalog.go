@@ -0,0 +1,292 @@
+package catalog
+
+import (
+	"html/template"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/markdown"
+)
+
+type Race struct {
+	ID   int    `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+type Profile struct {
+	ID              int    `json:"id"`
+	Slug            string `json:"slug"`
+	Name            string `json:"name"`
+	LifeDice        int    `json:"life_dice"`
+	MagicalStrength string `json:"magical_strength"`
+	ManaMaxCompute  int    `json:"mana_max_compute"`
+}
+
+type Path struct {
+	ID           int           `json:"id"`
+	Slug         string        `json:"slug"`
+	Name         string        `json:"name"`
+	Category     string        `json:"category"`
+	Profile      *Profile      `json:"profile"`
+	Race         *Race         `json:"race"`
+	Notes        string        `json:"notes"`
+	NotesHTML    template.HTML `json:"notes_html"`
+	Capabilities []*Capability `json:"capabilities"`
+}
+
+// Capability is a rank of a path. Spells are not a table of their own:
+// Django flags the capabilities that are spells.
+type Capability struct {
+	ID              int           `json:"id"`
+	Slug            string        `json:"slug"`
+	Name            string        `json:"name"`
+	Path            *Path         `json:"-"`
+	PathID          int           `json:"path_id"`
+	PathName        string        `json:"path_name"`
+	Rank            int           `json:"rank"`
+	Limited         bool          `json:"limited"`
+	Spell           bool          `json:"spell"`
+	Description     string        `json:"description"`
+	DescriptionHTML template.HTML `json:"description_html"`
+}
+
+// Catalog is a snapshot of the rules shared with Django, with the Markdown
+// texts already rendered.
+type Catalog struct {
+	Races        []*Race
+	Profiles     []*Profile
+	Paths        []*Path
+	Capabilities []*Capability
+
+	paths        map[int]*Path
+	raceSlugs    map[string]*Race
+	profileSlugs map[string]*Profile
+	pathSlugs    map[string]*Path
+}
+
+func load(db *database.DB, renderer *markdown.Renderer) (*Catalog, error) {
+	races, err := db.GetRaces()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := db.GetProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := db.GetPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	capabilities, err := db.GetCapabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{
+		paths:        map[int]*Path{},
+		raceSlugs:    map[string]*Race{},
+		profileSlugs: map[string]*Profile{},
+		pathSlugs:    map[string]*Path{},
+	}
+
+	racesByID := map[int]*Race{}
+	for _, r := range races {
+		race := &Race{ID: r.ID, Slug: Slug(r.Name), Name: r.Name}
+		racesByID[race.ID] = race
+		c.raceSlugs[race.Slug] = race
+		c.Races = append(c.Races, race)
+	}
+
+	profilesByID := map[int]*Profile{}
+	for _, p := range profiles {
+		profile := &Profile{ID: p.ID, Slug: Slug(p.Name), Name: p.Name, LifeDice: p.LifeDice, MagicalStrength: p.MagicalStrength, ManaMaxCompute: p.ManaMaxCompute}
+		profilesByID[profile.ID] = profile
+		c.profileSlugs[profile.Slug] = profile
+		c.Profiles = append(c.Profiles, profile)
+	}
+
+	for _, p := range paths {
+		path := &Path{ID: p.ID, Slug: Slug(p.Name), Name: p.Name, Category: p.Category, Notes: p.Notes, NotesHTML: renderer.Render(p.Notes)}
+
+		if p.ProfileID != nil {
+			path.Profile = profilesByID[*p.ProfileID]
+		}
+
+		if p.RaceID != nil {
+			path.Race = racesByID[*p.RaceID]
+		}
+
+		c.paths[path.ID] = path
+		c.pathSlugs[path.Slug] = path
+		c.Paths = append(c.Paths, path)
+	}
+
+	for _, row := range capabilities {
+		capability := &Capability{
+			ID:              row.ID,
+			Slug:            Slug(row.Name),
+			Name:            row.Name,
+			Path:            c.paths[row.PathID],
+			PathID:          row.PathID,
+			PathName:        row.PathName,
+			Rank:            row.Rank,
+			Limited:         row.Limited,
+			Spell:           row.Spell,
+			Description:     row.Description,
+			DescriptionHTML: renderer.Render(row.Description),
+		}
+
+		if capability.Path != nil {
+			capability.Path.Capabilities = append(capability.Path.Capabilities, capability)
+		}
+
+		c.Capabilities = append(c.Capabilities, capability)
+	}
+
+	return c, nil
+}
+
+func (c *Catalog) Path(id int) *Path {
+	return c.paths[id]
+}
+
+func (c *Catalog) RaceBySlug(slug string) *Race {
+	return c.raceSlugs[slug]
+}
+
+func (c *Catalog) ProfileBySlug(slug string) *Profile {
+	return c.profileSlugs[slug]
+}
+
+func (c *Catalog) PathBySlug(slug string) *Path {
+	return c.pathSlugs[slug]
+}
+
+// CapabilityBySlug looks the capability up within its path, since several
+// paths have capabilities of the same name.
+func (c *Catalog) CapabilityBySlug(pathSlug, slug string) *Capability {
+	path := c.pathSlugs[pathSlug]
+	if path == nil {
+		return nil
+	}
+
+	for _, capability := range path.Capabilities {
+		if capability.Slug == slug {
+			return capability
+		}
+	}
+
+	return nil
+}
+
+// Filter selects paths and capabilities. Zero values match everything.
+type Filter struct {
+	ProfileID int    `form:"profile"`
+	RaceID    int    `form:"race"`
+	Category  string `form:"category"`
+	PathID    int    `form:"path"`
+	Spell     bool   `form:"spell"`
+	Query     string `form:"q"`
+}
+
+func (f Filter) matchesPath(path *Path) bool {
+	switch {
+	case f.ProfileID != 0 && (path.Profile == nil || path.Profile.ID != f.ProfileID):
+		return false
+	case f.RaceID != 0 && (path.Race == nil || path.Race.ID != f.RaceID):
+		return false
+	case f.Category != "" && path.Category != f.Category:
+		return false
+	case f.PathID != 0 && path.ID != f.PathID:
+		return false
+	}
+
+	return true
+}
+
+func (c *Catalog) FilterPaths(f Filter) []*Path {
+	paths := []*Path{}
+
+	for _, path := range c.Paths {
+		if f.matchesPath(path) && matchesQuery(f.Query, path.Name) {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+func (c *Catalog) FilterCapabilities(f Filter) []*Capability {
+	capabilities := []*Capability{}
+
+	for _, capability := range c.Capabilities {
+		switch {
+		case capability.Path == nil || !f.matchesPath(capability.Path):
+		case f.Spell && !capability.Spell:
+		case !matchesQuery(f.Query, capability.Name):
+		default:
+			capabilities = append(capabilities, capability)
+		}
+	}
+
+	return capabilities
+}
+
+// Categories lists the path categories in use, in catalog order.
+func (c *Catalog) Categories() []string {
+	var categories []string
+
+	for _, path := range c.Paths {
+		if !slices.Contains(categories, path.Category) {
+			categories = append(categories, path.Category)
+		}
+	}
+
+	return categories
+}
+
+func matchesQuery(query, name string) bool {
+	return strings.Contains(strings.ToLower(name), strings.ToLower(strings.TrimSpace(query)))
+}
+
+// Cache keeps the catalog in memory, loading it again once it is older than
+// the TTL.
+type Cache struct {
+	db       *database.DB
+	renderer *markdown.Renderer
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	catalog *Catalog
+	expires time.Time
+}
+
+func NewCache(db *database.DB, renderer *markdown.Renderer, ttl time.Duration) *Cache {
+	return &Cache{db: db, renderer: renderer, ttl: ttl}
+}
+
+func (c *Cache) Get() (*Catalog, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.catalog != nil && time.Now().Before(c.expires) {
+		return c.catalog, nil
+	}
+
+	catalog, err := load(c.db, c.renderer)
+	if err != nil {
+		return nil, err
+	}
+
+	c.catalog = catalog
+	c.expires = time.Now().Add(c.ttl)
+
+	return catalog, nil
+}
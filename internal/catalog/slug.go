@@ -0,0 +1,40 @@
+package catalog
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Slug identifies catalog entries across instances, where ids differ:
+// "Voie de l'épée" becomes "voie-de-l-epee".
+func Slug(name string) string {
+	var sb strings.Builder
+
+	dash := false
+
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+
+			sb.WriteRune(r)
+			dash = false
+		case r == 'œ':
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+
+			sb.WriteString("oe")
+			dash = false
+		default:
+			dash = true
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,376 @@
+// Package charjson defines the JSON format characters are exported in, so
+// that they can be moved between instances.
+//
+// A document names its format and version, and holds one character:
+//
+//	{
+//		"format": "charasheet-character",
+//		"version": 2,
+//		"exported": "2024-01-31T20:00:00Z",
+//		"character": {
+//			"name": "Aldric",
+//			"race": {"slug": "humain", "name": "Humain"},
+//			"profile": {"slug": "guerrier", "name": "Guerrier"},
+//			"level": 3, "gender": "M", "age": 30, "height": 180, "weight": 80,
+//			"abilities": {"FOR": 15, "DEX": 12, "CON": 14, "INT": 8, "SAG": 10, "CHA": 13},
+//			"health_max": 30, "armor": 4, "shield": 2, "defense_misc": 0, "initiative_misc": 0,
+//			"damage_reduction": "",
+//			"counters": {"health": 25, "mana": 0, "recovery_points": 5, "luck_points": 3},
+//			"money": {"pp": 0, "po": 12, "pa": 5, "pc": 0},
+//			"equipment": "Épée longue, sac à dos",
+//			"inventory": [
+//				{"id": 1, "name": "Sac à dos", "quantity": 1, "weight": 1, "container_id": null,
+//				 "is_container": true, "equipped": false, "slot": "", "defense": 0, "damage": ""}
+//			],
+//			"capabilities": [
+//				{"slug": "parade", "name": "Parade", "rank": 1,
+//				 "path": {"slug": "voie-du-bouclier", "name": "Voie du bouclier"}}
+//			],
+//			"notes": "Markdown",
+//			"portrait": "profile_pictures/aldric.png"
+//		}
+//	}
+//
+// Catalog entries are referred to by slug, since ids differ between
+// instances; their names are only kept for messages. Inventory ids are local
+// to the document, container_id refers to them. The portrait is the path of
+// the file on the exporting instance, the file itself is not included.
+//
+// Documents of older versions are upgraded on decoding, one version at a
+// time: changing the format means incrementing Version and adding the
+// upgrade from the previous version to upgrades.
+package charjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Crocmagnon/charasheet-go/internal/catalog"
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/rules"
+	"github.com/Crocmagnon/charasheet-go/internal/validator"
+)
+
+const (
+	Format  = "charasheet-character"
+	Version = 2
+)
+
+// upgrades maps a version to the function rewriting a document of that
+// version into the next one.
+var upgrades = map[int]func(document map[string]any) error{
+	// Version 2 added the damage of the weapons.
+	1: func(document map[string]any) error {
+		character, ok := document["character"].(map[string]any)
+		if !ok {
+			return errors.New("character must be a JSON object")
+		}
+
+		inventory, _ := character["inventory"].([]any)
+		for _, item := range inventory {
+			if item, ok := item.(map[string]any); ok {
+				item["damage"] = ""
+			}
+		}
+
+		return nil
+	},
+}
+
+type Document struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	Exported  time.Time `json:"exported"`
+	Character Character `json:"character"`
+}
+
+type Character struct {
+	Name            string       `json:"name"`
+	Race            Ref          `json:"race"`
+	Profile         Ref          `json:"profile"`
+	Level           int          `json:"level"`
+	Gender          string       `json:"gender"`
+	Age             int          `json:"age"`
+	Height          int          `json:"height"`
+	Weight          int          `json:"weight"`
+	Abilities       Abilities    `json:"abilities"`
+	HealthMax       int          `json:"health_max"`
+	Armor           int          `json:"armor"`
+	Shield          int          `json:"shield"`
+	DefenseMisc     int          `json:"defense_misc"`
+	InitiativeMisc  int          `json:"initiative_misc"`
+	DamageReduction string       `json:"damage_reduction"`
+	Counters        Counters     `json:"counters"`
+	Money           Money        `json:"money"`
+	Equipment       string       `json:"equipment"`
+	Inventory       []Item       `json:"inventory"`
+	Capabilities    []Capability `json:"capabilities"`
+	Notes           string       `json:"notes"`
+	Portrait        string       `json:"portrait"`
+}
+
+// Ref refers to a catalog entry.
+type Ref struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+type Abilities struct {
+	Strength     int `json:"FOR"`
+	Dexterity    int `json:"DEX"`
+	Constitution int `json:"CON"`
+	Intelligence int `json:"INT"`
+	Wisdom       int `json:"SAG"`
+	Charisma     int `json:"CHA"`
+}
+
+type Counters struct {
+	Health         int `json:"health"`
+	Mana           int `json:"mana"`
+	RecoveryPoints int `json:"recovery_points"`
+	LuckPoints     int `json:"luck_points"`
+}
+
+type Money struct {
+	PP int `json:"pp"`
+	PO int `json:"po"`
+	PA int `json:"pa"`
+	PC int `json:"pc"`
+}
+
+type Item struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Quantity    int     `json:"quantity"`
+	Weight      float64 `json:"weight"`
+	ContainerID *int    `json:"container_id"`
+	IsContainer bool    `json:"is_container"`
+	Equipped    bool    `json:"equipped"`
+	Slot        string  `json:"slot"`
+	Defense     int     `json:"defense"`
+	Damage      string  `json:"damage"`
+}
+
+type Capability struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+	Rank int    `json:"rank"`
+	Path Ref    `json:"path"`
+}
+
+// New builds the document of a character, its capabilities and inventory.
+func New(c *database.Character, capabilities []database.Capability, items []database.InventoryItem, exported time.Time) *Document {
+	character := Character{
+		Name:    c.Name,
+		Race:    Ref{Slug: catalog.Slug(c.RaceName), Name: c.RaceName},
+		Profile: Ref{Slug: catalog.Slug(c.ProfileName), Name: c.ProfileName},
+		Level:   c.Level,
+		Gender:  c.Gender,
+		Age:     c.Age,
+		Height:  c.Height,
+		Weight:  c.Weight,
+		Abilities: Abilities{
+			Strength:     c.ValueStrength,
+			Dexterity:    c.ValueDexterity,
+			Constitution: c.ValueConstitution,
+			Intelligence: c.ValueIntelligence,
+			Wisdom:       c.ValueWisdom,
+			Charisma:     c.ValueCharisma,
+		},
+		HealthMax:       c.HealthMax,
+		Armor:           c.Armor,
+		Shield:          c.Shield,
+		DefenseMisc:     c.DefenseMisc,
+		InitiativeMisc:  c.InitiativeMisc,
+		DamageReduction: c.DamageReduction,
+		Counters: Counters{
+			Health:         c.HealthRemaining,
+			Mana:           c.ManaRemaining,
+			RecoveryPoints: c.RecoveryPointsRemaining,
+			LuckPoints:     c.LuckPointsRemaining,
+		},
+		Money:        Money{PP: c.MoneyPP, PO: c.MoneyPO, PA: c.MoneyPA, PC: c.MoneyPC},
+		Equipment:    c.Equipment,
+		Inventory:    []Item{},
+		Capabilities: []Capability{},
+		Notes:        c.Notes,
+		Portrait:     c.ProfilePicture,
+	}
+
+	for _, item := range items {
+		character.Inventory = append(character.Inventory, Item{
+			ID:          item.ID,
+			Name:        item.Name,
+			Quantity:    item.Quantity,
+			Weight:      item.Weight,
+			ContainerID: item.ContainerID,
+			IsContainer: item.IsContainer,
+			Equipped:    item.Equipped,
+			Slot:        item.Slot,
+			Defense:     item.Defense,
+			Damage:      item.Damage,
+		})
+	}
+
+	for _, capability := range capabilities {
+		character.Capabilities = append(character.Capabilities, Capability{
+			Slug: catalog.Slug(capability.Name),
+			Name: capability.Name,
+			Rank: capability.Rank,
+			Path: Ref{Slug: catalog.Slug(capability.PathName), Name: capability.PathName},
+		})
+	}
+
+	return &Document{Format: Format, Version: Version, Exported: exported, Character: character}
+}
+
+// Decode reads a document of any supported version, upgrading it to the
+// current one. Keys that are not part of the format are rejected.
+func Decode(r io.Reader) (*Document, error) {
+	var raw map[string]any
+
+	err := json.NewDecoder(r).Decode(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("document is not valid JSON: %w", err)
+	}
+
+	if raw["format"] != Format {
+		return nil, fmt.Errorf("document is not a %s document", Format)
+	}
+
+	version, ok := raw["version"].(float64)
+	if !ok || version != float64(int(version)) || version < 1 {
+		return nil, errors.New("document version is invalid")
+	}
+
+	if int(version) > Version {
+		return nil, fmt.Errorf("document version %d is newer than the supported version %d", int(version), Version)
+	}
+
+	for v := int(version); v < Version; v++ {
+		upgrade, ok := upgrades[v]
+		if !ok {
+			return nil, fmt.Errorf("document version %d cannot be upgraded", v)
+		}
+
+		err = upgrade(raw)
+		if err != nil {
+			return nil, fmt.Errorf("upgrading document version %d: %w", v, err)
+		}
+
+		raw["version"] = float64(v + 1)
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(&document)
+	if err != nil {
+		var typeError *json.UnmarshalTypeError
+
+		switch {
+		case errors.As(err, &typeError):
+			return nil, fmt.Errorf("%s must be a JSON %s", typeError.Field, typeError.Type.Kind())
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return nil, fmt.Errorf("document contains unknown key %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		default:
+			return nil, err
+		}
+	}
+
+	return &document, nil
+}
+
+// Validate checks the values of the character, reporting errors by their
+// JSON path. Catalog references are resolved by the importer.
+func (d *Document) Validate(v *validator.Validator) {
+	c := d.Character
+
+	v.CheckField(strings.TrimSpace(c.Name) != "", "character.name", "Name is required")
+	v.CheckField(utf8.RuneCountInString(c.Name) <= 100, "character.name", "Name is too long")
+	v.CheckField(c.Race.Slug != "", "character.race.slug", "Race is required")
+	v.CheckField(c.Profile.Slug != "", "character.profile.slug", "Profile is required")
+	v.CheckField(c.Level >= 1, "character.level", "Level must be at least 1")
+	v.CheckField(c.Age >= 0, "character.age", "Age must not be negative")
+	v.CheckField(c.Height >= 0, "character.height", "Height must not be negative")
+	v.CheckField(c.Weight >= 0, "character.weight", "Weight must not be negative")
+
+	abilities := map[rules.Ability]int{
+		rules.Strength:     c.Abilities.Strength,
+		rules.Dexterity:    c.Abilities.Dexterity,
+		rules.Constitution: c.Abilities.Constitution,
+		rules.Intelligence: c.Abilities.Intelligence,
+		rules.Wisdom:       c.Abilities.Wisdom,
+		rules.Charisma:     c.Abilities.Charisma,
+	}
+
+	for _, ability := range rules.Abilities {
+		v.CheckField(abilities[ability] >= 1, "character.abilities."+string(ability), "Ability must be at least 1")
+	}
+
+	v.CheckField(c.HealthMax >= 1, "character.health_max", "Maximum health must be at least 1")
+	v.CheckField(c.Armor >= 0, "character.armor", "Armor must not be negative")
+	v.CheckField(c.Shield >= 0, "character.shield", "Shield must not be negative")
+	v.CheckField(c.Counters.Health >= 0 && c.Counters.Health <= c.HealthMax, "character.counters.health", "Health must be between 0 and the maximum health")
+	v.CheckField(c.Counters.Mana >= 0, "character.counters.mana", "Mana must not be negative")
+	v.CheckField(c.Counters.RecoveryPoints >= 0 && c.Counters.RecoveryPoints <= rules.RecoveryPointsMax, "character.counters.recovery_points", fmt.Sprintf("Recovery points must be between 0 and %d", rules.RecoveryPointsMax))
+	v.CheckField(c.Counters.LuckPoints >= 0, "character.counters.luck_points", "Luck points must not be negative")
+	v.CheckField(c.Money.PP >= 0 && c.Money.PO >= 0 && c.Money.PA >= 0 && c.Money.PC >= 0, "character.money", "Money must not be negative")
+
+	containers := map[int]*Item{}
+	ids := map[int]bool{}
+
+	for i := range c.Inventory {
+		item := &c.Inventory[i]
+		key := fmt.Sprintf("character.inventory[%d]", i)
+
+		v.CheckField(!ids[item.ID], key+".id", "Id is not unique")
+		ids[item.ID] = true
+
+		if item.IsContainer {
+			containers[item.ID] = item
+		}
+
+		v.CheckField(strings.TrimSpace(item.Name) != "", key+".name", "Name is required")
+		v.CheckField(item.Quantity >= 1, key+".quantity", "Quantity must be at least 1")
+		v.CheckField(item.Weight >= 0, key+".weight", "Weight must not be negative")
+		v.CheckField(item.Slot == "" || slices.Contains(rules.Slots, item.Slot), key+".slot", "Slot is invalid")
+		v.CheckField(item.Defense >= 0, key+".defense", "Defense must not be negative")
+
+		if item.Damage != "" {
+			_, err := rules.ParseDice(item.Damage)
+			v.CheckField(err == nil, key+".damage", "Damage is not a valid dice expression")
+		}
+	}
+
+	for i, item := range c.Inventory {
+		if item.ContainerID == nil {
+			continue
+		}
+
+		container := containers[*item.ContainerID]
+		valid := container != nil && container.ContainerID == nil && container.ID != item.ID && !item.IsContainer
+		v.CheckField(valid, fmt.Sprintf("character.inventory[%d].container_id", i), "Container is invalid")
+	}
+
+	for i, capability := range c.Capabilities {
+		key := fmt.Sprintf("character.capabilities[%d]", i)
+
+		v.CheckField(capability.Slug != "", key+".slug", "Capability is required")
+		v.CheckField(capability.Path.Slug != "", key+".path.slug", "Path is required")
+	}
+}
@@ -0,0 +1,206 @@
+package charjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/validator"
+)
+
+func TestDecodeVersion1(t *testing.T) {
+	f, err := os.Open("testdata/character-v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	document, err := Decode(f)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if document.Version != Version {
+		t.Errorf("Version = %d, want %d", document.Version, Version)
+	}
+
+	c := document.Character
+
+	if c.Name != "Aldric" || c.Race.Slug != "humain" || c.Abilities.Constitution != 14 || c.Money.PO != 12 {
+		t.Errorf("Character = %+v", c)
+	}
+
+	if len(c.Inventory) != 3 {
+		t.Fatalf("len(Inventory) = %d, want 3", len(c.Inventory))
+	}
+
+	for _, item := range c.Inventory {
+		if item.Damage != "" {
+			t.Errorf("Inventory item %q Damage = %q, want empty", item.Name, item.Damage)
+		}
+	}
+
+	if c.Inventory[2].ContainerID == nil || *c.Inventory[2].ContainerID != 1 {
+		t.Errorf("Inventory[2].ContainerID = %v, want 1", c.Inventory[2].ContainerID)
+	}
+
+	var v validator.Validator
+	document.Validate(&v)
+
+	if v.HasErrors() {
+		t.Errorf("Validate() errors = %v", v.FieldErrors)
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	container := 1
+	exported := time.Date(2024, time.January, 31, 20, 0, 0, 0, time.UTC)
+
+	want := New(
+		&database.Character{
+			Name: "Nimue", RaceName: "Elfe", ProfileName: "Magicienne", Level: 2,
+			ValueStrength: 8, ValueDexterity: 14, ValueConstitution: 10,
+			ValueIntelligence: 17, ValueWisdom: 12, ValueCharisma: 13,
+			HealthMax: 12, HealthRemaining: 12, ManaRemaining: 6, RecoveryPointsRemaining: 5,
+			Notes: "# Grimoire",
+		},
+		[]database.Capability{{Name: "Projectile de force", PathName: "Voie de la magie destructrice", Rank: 1}},
+		[]database.InventoryItem{
+			{ID: 1, Name: "Sacoche", Quantity: 1, IsContainer: true},
+			{ID: 2, Name: "Bâton", Quantity: 1, Weight: 1.5, Equipped: true, Slot: "melee", Damage: "1d6+@FOR", ContainerID: nil},
+			{ID: 3, Name: "Parchemin", Quantity: 2, ContainerID: &container},
+		},
+		exported,
+	)
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid, err := os.ReadFile("testdata/character-v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{
+			name:     "not JSON",
+			document: "{",
+			want:     "document is not valid JSON",
+		},
+		{
+			name:     "other format",
+			document: `{"format": "other", "version": 1}`,
+			want:     "document is not a charasheet-character document",
+		},
+		{
+			name:     "unsupported version",
+			document: strings.Replace(string(valid), `"version": 1`, `"version": 3`, 1),
+			want:     "document version 3 is newer than the supported version 2",
+		},
+		{
+			name:     "version 0",
+			document: strings.Replace(string(valid), `"version": 1`, `"version": 0`, 1),
+			want:     "document version is invalid",
+		},
+		{
+			name:     "fractional version",
+			document: strings.Replace(string(valid), `"version": 1`, `"version": 1.5`, 1),
+			want:     "document version is invalid",
+		},
+		{
+			name:     "unknown top-level key",
+			document: strings.Replace(string(valid), `"version": 1,`, `"version": 1, "owner": "p1",`, 1),
+			want:     `document contains unknown key "owner"`,
+		},
+		{
+			name:     "unknown character key",
+			document: strings.Replace(string(valid), `"level": 3,`, `"level": 3, "xp": 1200,`, 1),
+			want:     `document contains unknown key "xp"`,
+		},
+		{
+			name:     "wrong type",
+			document: strings.Replace(string(valid), `"level": 3,`, `"level": "3",`, 1),
+			want:     "character.level must be a JSON int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tt.document))
+			if err == nil {
+				t.Fatal("Decode() error = nil")
+			}
+
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("Decode() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Character)
+		key    string
+	}{
+		{"empty name", func(c *Character) { c.Name = " " }, "character.name"},
+		{"missing race", func(c *Character) { c.Race.Slug = "" }, "character.race.slug"},
+		{"level 0", func(c *Character) { c.Level = 0 }, "character.level"},
+		{"ability 0", func(c *Character) { c.Abilities.Wisdom = 0 }, "character.abilities.SAG"},
+		{"health above maximum", func(c *Character) { c.Counters.Health = 31 }, "character.counters.health"},
+		{"too many recovery points", func(c *Character) { c.Counters.RecoveryPoints = 6 }, "character.counters.recovery_points"},
+		{"negative money", func(c *Character) { c.Money.PC = -1 }, "character.money"},
+		{"duplicate item id", func(c *Character) { c.Inventory[1].ID = 1 }, "character.inventory[1].id"},
+		{"unknown slot", func(c *Character) { c.Inventory[1].Slot = "head" }, "character.inventory[1].slot"},
+		{"invalid damage", func(c *Character) { c.Inventory[1].Damage = "1d8+@XYZ" }, "character.inventory[1].damage"},
+		{"container is not a container", func(c *Character) { c.Inventory[2].ContainerID = &c.Inventory[1].ID }, "character.inventory[2].container_id"},
+		{"missing path", func(c *Character) { c.Capabilities[0].Path.Slug = "" }, "character.capabilities[0].path.slug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open("testdata/character-v1.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			document, err := Decode(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.change(&document.Character)
+
+			var v validator.Validator
+			document.Validate(&v)
+
+			if _, ok := v.FieldErrors[tt.key]; !ok || len(v.FieldErrors) != 1 {
+				t.Errorf("Validate() errors = %v, want one on %s", v.FieldErrors, tt.key)
+			}
+		})
+	}
+}
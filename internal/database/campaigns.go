@@ -0,0 +1,351 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Campaigns are the parties of the Django app: a game master, the members
+// they play with and the characters of the members.
+
+type Campaign struct {
+	ID             int       `db:"id"`
+	Name           string    `db:"name"`
+	GameMasterID   int       `db:"game_master_id"`
+	GameMasterName string    `db:"game_master_name"`
+	Created        time.Time `db:"created"`
+	Modified       time.Time `db:"modified"`
+}
+
+const campaignSelect = `
+	SELECT p.id, p.name, p.game_master_id, u.username AS game_master_name, p.created, p.modified
+	FROM party_party p
+	JOIN common_user u ON u.id = p.game_master_id`
+
+func (db *DB) GetCampaign(id int) (*Campaign, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var campaign Campaign
+
+	query := campaignSelect + ` WHERE p.id = $1`
+
+	err := db.GetContext(ctx, &campaign, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &campaign, err
+}
+
+// GetUserCampaigns returns the campaigns the user runs or is a member of.
+func (db *DB) GetUserCampaigns(userID int) ([]Campaign, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var campaigns []Campaign
+
+	query := campaignSelect + `
+		WHERE p.game_master_id = $1
+		OR p.id IN (SELECT party_id FROM party_party_members WHERE user_id = $1)
+		ORDER BY p.name`
+
+	err := db.SelectContext(ctx, &campaigns, query, userID)
+	return campaigns, err
+}
+
+func (db *DB) InsertCampaign(name string, gameMasterID int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `INSERT INTO party_party (name, game_master_id, created, modified) VALUES ($1, $2, $3, $3)`
+
+	result, err := db.ExecContext(ctx, query, name, gameMasterID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), err
+}
+
+type CampaignMember struct {
+	UserID   int    `db:"user_id"`
+	Username string `db:"username"`
+	Email    string `db:"email"`
+}
+
+func (db *DB) GetCampaignMembers(campaignID int) ([]CampaignMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var members []CampaignMember
+
+	query := `
+		SELECT u.id AS user_id, u.username, u.email
+		FROM party_party_members m
+		JOIN common_user u ON u.id = m.user_id
+		WHERE m.party_id = $1
+		ORDER BY u.username`
+
+	err := db.SelectContext(ctx, &members, query, campaignID)
+	return members, err
+}
+
+func (db *DB) IsCampaignMember(campaignID, userID int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var exists bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM party_party_members WHERE party_id = $1 AND user_id = $2)`
+
+	err := db.GetContext(ctx, &exists, query, campaignID, userID)
+	return exists, err
+}
+
+// RemoveCampaignMember also takes the characters of the member out of the
+// campaign.
+func (db *DB) RemoveCampaignMember(campaignID, userID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM party_party_members WHERE party_id = $1 AND user_id = $2`
+
+	_, err = tx.ExecContext(ctx, query, campaignID, userID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		DELETE FROM party_party_characters
+		WHERE party_id = $1 AND character_id IN (SELECT id FROM character_character WHERE player_id = $2)`
+
+	_, err = tx.ExecContext(ctx, query, campaignID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type CampaignCharacter struct {
+	ID         int    `db:"id"`
+	Name       string `db:"name"`
+	PlayerID   int    `db:"player_id"`
+	PlayerName string `db:"player_name"`
+	Level      int    `db:"level"`
+}
+
+func (db *DB) GetCampaignCharacters(campaignID int) ([]CampaignCharacter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var characters []CampaignCharacter
+
+	query := `
+		SELECT c.id, c.name, c.player_id, u.username AS player_name, c.level
+		FROM party_party_characters pc
+		JOIN character_character c ON c.id = pc.character_id
+		JOIN common_user u ON u.id = c.player_id
+		WHERE pc.party_id = $1
+		ORDER BY c.name`
+
+	err := db.SelectContext(ctx, &characters, query, campaignID)
+	return characters, err
+}
+
+// GetPlayerCharacters returns the characters of the player, for them to
+// choose one to bring in a campaign.
+func (db *DB) GetPlayerCharacters(playerID int) ([]CharacterSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var characters []CharacterSummary
+
+	query := `SELECT id, name FROM character_character WHERE player_id = $1 ORDER BY name`
+
+	err := db.SelectContext(ctx, &characters, query, playerID)
+	return characters, err
+}
+
+func (db *DB) AddCampaignCharacter(campaignID, characterID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO party_party_characters (party_id, character_id)
+		SELECT $1, $2
+		WHERE NOT EXISTS(SELECT 1 FROM party_party_characters WHERE party_id = $1 AND character_id = $2)`
+
+	_, err := db.ExecContext(ctx, query, campaignID, characterID)
+	return err
+}
+
+func (db *DB) RemoveCampaignCharacter(campaignID, characterID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `DELETE FROM party_party_characters WHERE party_id = $1 AND character_id = $2`
+
+	_, err := db.ExecContext(ctx, query, campaignID, characterID)
+	return err
+}
+
+type CampaignInvitation struct {
+	HashedToken  string    `db:"hashed_token"`
+	CampaignID   int       `db:"campaign_id"`
+	CampaignName string    `db:"campaign_name"`
+	Email        string    `db:"email"`
+	InvitedByID  int       `db:"invited_by_id"`
+	Created      time.Time `db:"created"`
+	Expiry       time.Time `db:"expiry"`
+}
+
+const campaignInvitationSelect = `
+	SELECT i.hashed_token, i.campaign_id, p.name AS campaign_name, i.email, i.invited_by_id, i.created, i.expiry
+	FROM campaign_invitations i
+	JOIN party_party p ON p.id = i.campaign_id`
+
+// InsertCampaignInvitation replaces any pending invitation of the email to
+// the campaign, so that only the latest link works.
+func (db *DB) InsertCampaignInvitation(hashedToken string, campaignID int, email string, invitedByID int, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM campaign_invitations WHERE campaign_id = $1 AND email = $2`
+
+	_, err = tx.ExecContext(ctx, query, campaignID, email)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO campaign_invitations (hashed_token, campaign_id, email, invited_by_id, created, expiry)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	now := time.Now()
+
+	_, err = tx.ExecContext(ctx, query, hashedToken, campaignID, email, invitedByID, now, now.Add(ttl))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) GetCampaignInvitation(hashedToken string) (*CampaignInvitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var invitation CampaignInvitation
+
+	query := campaignInvitationSelect + ` WHERE i.hashed_token = $1 AND i.expiry > $2`
+
+	err := db.GetContext(ctx, &invitation, query, hashedToken, time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &invitation, err
+}
+
+func (db *DB) GetCampaignInvitations(campaignID int) ([]CampaignInvitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var invitations []CampaignInvitation
+
+	query := campaignInvitationSelect + ` WHERE i.campaign_id = $1 AND i.expiry > $2 ORDER BY i.email`
+
+	err := db.SelectContext(ctx, &invitations, query, campaignID, time.Now())
+	return invitations, err
+}
+
+func (db *DB) DeleteCampaignInvitation(hashedToken string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `DELETE FROM campaign_invitations WHERE hashed_token = $1`
+
+	_, err := db.ExecContext(ctx, query, hashedToken)
+	return err
+}
+
+// AcceptCampaignInvitation makes the user a member of the campaign of the
+// invitation, once.
+func (db *DB) AcceptCampaignInvitation(invitation *CampaignInvitation, userID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM campaign_invitations WHERE hashed_token = $1`
+
+	result, err := tx.ExecContext(ctx, query, invitation.HashedToken)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return tx.Commit()
+	}
+
+	query = `
+		INSERT INTO party_party_members (party_id, user_id)
+		SELECT $1, $2
+		WHERE NOT EXISTS(SELECT 1 FROM party_party_members WHERE party_id = $1 AND user_id = $2)`
+
+	_, err = tx.ExecContext(ctx, query, invitation.CampaignID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsCharacterCampaignMember reports whether the user is a member of a
+// campaign the character plays in.
+func (db *DB) IsCharacterCampaignMember(characterID, userID int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var exists bool
+
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM party_party_characters pc
+			JOIN party_party_members m ON m.party_id = pc.party_id
+			WHERE pc.character_id = $1 AND m.user_id = $2
+		)`
+
+	err := db.GetContext(ctx, &exists, query, characterID, userID)
+	return exists, err
+}
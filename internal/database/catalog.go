@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type Race struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+type Profile struct {
+	ID              int    `db:"id"`
+	Name            string `db:"name"`
+	LifeDice        int    `db:"life_dice"`
+	MagicalStrength string `db:"magical_strength"`
+	ManaMaxCompute  int    `db:"mana_max_compute"`
+}
+
+func (db *DB) GetRaces() ([]Race, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var races []Race
+
+	query := `SELECT id, name FROM character_race ORDER BY name`
+
+	err := db.SelectContext(ctx, &races, query)
+	return races, err
+}
+
+func (db *DB) GetRace(id int) (*Race, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var race Race
+
+	query := `SELECT id, name FROM character_race WHERE id = $1`
+
+	err := db.GetContext(ctx, &race, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &race, err
+}
+
+func (db *DB) GetProfiles() ([]Profile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var profiles []Profile
+
+	query := `SELECT id, name, life_dice, magical_strength, mana_max_compute FROM character_profile ORDER BY name`
+
+	err := db.SelectContext(ctx, &profiles, query)
+	return profiles, err
+}
+
+func (db *DB) GetProfile(id int) (*Profile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var profile Profile
+
+	query := `SELECT id, name, life_dice, magical_strength, mana_max_compute FROM character_profile WHERE id = $1`
+
+	err := db.GetContext(ctx, &profile, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &profile, err
+}
+
+type Capability struct {
+	ID          int    `db:"id"`
+	Name        string `db:"name"`
+	PathID      int    `db:"path_id"`
+	PathName    string `db:"path_name"`
+	Rank        int    `db:"rank"`
+	Limited     bool   `db:"limited"`
+	Spell       bool   `db:"spell"`
+	Description string `db:"description"`
+}
+
+const capabilitySelect = `
+	SELECT c.id, c.name, c.path_id, p.name AS path_name, c.rank, c.limited, c.spell, c.description
+	FROM character_capability c
+	JOIN character_path p ON p.id = c.path_id`
+
+func (db *DB) GetCharacterCapabilities(characterID int) ([]Capability, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var capabilities []Capability
+
+	query := capabilitySelect + `
+		JOIN character_character_capabilities cc ON cc.capability_id = c.id
+		WHERE cc.character_id = $1
+		ORDER BY p.name, c.rank`
+
+	err := db.SelectContext(ctx, &capabilities, query, characterID)
+	return capabilities, err
+}
+
+// GetPathCapabilities returns the capabilities of the paths a character can
+// progress in: the paths of its profile and race, and the paths it already
+// has capabilities in.
+func (db *DB) GetPathCapabilities(character *Character) ([]Capability, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var capabilities []Capability
+
+	query := capabilitySelect + `
+		WHERE p.profile_id = $1 OR p.race_id = $2 OR p.id IN (
+			SELECT owned.path_id
+			FROM character_capability owned
+			JOIN character_character_capabilities cc ON cc.capability_id = owned.id
+			WHERE cc.character_id = $3
+		)
+		ORDER BY p.name, c.rank`
+
+	err := db.SelectContext(ctx, &capabilities, query, character.ProfileID, character.RaceID, character.ID)
+	return capabilities, err
+}
+
+type Path struct {
+	ID          int     `db:"id"`
+	Name        string  `db:"name"`
+	Category    string  `db:"category"`
+	ProfileID   *int    `db:"profile_id"`
+	ProfileName *string `db:"profile_name"`
+	RaceID      *int    `db:"race_id"`
+	RaceName    *string `db:"race_name"`
+	Notes       string  `db:"notes"`
+}
+
+func (db *DB) GetPaths() ([]Path, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var paths []Path
+
+	query := `
+		SELECT p.id, p.name, p.category, p.profile_id, pr.name AS profile_name, p.race_id, r.name AS race_name, p.notes
+		FROM character_path p
+		LEFT JOIN character_profile pr ON pr.id = p.profile_id
+		LEFT JOIN character_race r ON r.id = p.race_id
+		ORDER BY p.name`
+
+	err := db.SelectContext(ctx, &paths, query)
+	return paths, err
+}
+
+func (db *DB) GetCapabilities() ([]Capability, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var capabilities []Capability
+
+	query := capabilitySelect + ` ORDER BY p.name, c.rank`
+
+	err := db.SelectContext(ctx, &capabilities, query)
+	return capabilities, err
+}
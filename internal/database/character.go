@@ -2,37 +2,340 @@ package database
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Crocmagnon/charasheet-go/internal/diff"
+	"github.com/jmoiron/sqlx"
 )
 
 type Character struct {
-	ID    int    `db:"id"`
-	Notes string `db:"notes"`
+	ID                      int    `db:"id"`
+	Name                    string `db:"name"`
+	PlayerID                int    `db:"player_id"`
+	PlayerName              string `db:"player_name"`
+	RaceID                  int    `db:"race_id"`
+	RaceName                string `db:"race_name"`
+	ProfileID               int    `db:"profile_id"`
+	ProfileName             string `db:"profile_name"`
+	ProfileLifeDice         int    `db:"profile_life_dice"`
+	ProfileMagicalStrength  string `db:"profile_magical_strength"`
+	ProfileManaMaxCompute   int    `db:"profile_mana_max_compute"`
+	Level                   int    `db:"level"`
+	Gender                  string `db:"gender"`
+	Age                     int    `db:"age"`
+	Height                  int    `db:"height"`
+	Weight                  int    `db:"weight"`
+	ValueStrength           int    `db:"value_strength"`
+	ValueDexterity          int    `db:"value_dexterity"`
+	ValueConstitution       int    `db:"value_constitution"`
+	ValueIntelligence       int    `db:"value_intelligence"`
+	ValueWisdom             int    `db:"value_wisdom"`
+	ValueCharisma           int    `db:"value_charisma"`
+	HealthMax               int    `db:"health_max"`
+	HealthRemaining         int    `db:"health_remaining"`
+	Armor                   int    `db:"armor"`
+	Shield                  int    `db:"shield"`
+	DefenseMisc             int    `db:"defense_misc"`
+	InitiativeMisc          int    `db:"initiative_misc"`
+	ManaRemaining           int    `db:"mana_remaining"`
+	RecoveryPointsRemaining int    `db:"recovery_points_remaining"`
+	LuckPointsRemaining     int    `db:"luck_points_remaining"`
+	Equipment               string `db:"equipment"`
+	MoneyPP                 int    `db:"money_pp"`
+	MoneyPO                 int    `db:"money_po"`
+	MoneyPA                 int    `db:"money_pa"`
+	MoneyPC                 int    `db:"money_pc"`
+	DamageReduction         string `db:"damage_reduction"`
+	Notes                   string `db:"notes"`
+	ProfilePicture          string `db:"profile_picture"`
+
+	// Effects are the active effects, loaded by GetCharacter.
+	Effects []Effect `db:"-"`
 }
 
+const characterSelect = `
+	SELECT
+		c.id, c.name, c.player_id, u.username AS player_name,
+		c.race_id, r.name AS race_name, c.profile_id, p.name AS profile_name,
+		p.life_dice AS profile_life_dice, p.magical_strength AS profile_magical_strength,
+		p.mana_max_compute AS profile_mana_max_compute,
+		c.level, c.gender, c.age, c.height, c.weight,
+		c.value_strength, c.value_dexterity, c.value_constitution,
+		c.value_intelligence, c.value_wisdom, c.value_charisma,
+		c.health_max, c.health_remaining, c.armor, c.shield, c.defense_misc, c.initiative_misc,
+		c.mana_remaining, c.recovery_points_remaining, c.luck_points_remaining,
+		c.equipment, c.money_pp, c.money_po, c.money_pa, c.money_pc,
+		c.damage_reduction, c.notes, c.profile_picture
+	FROM character_character c
+	JOIN common_user u ON u.id = c.player_id
+	JOIN character_race r ON r.id = c.race_id
+	JOIN character_profile p ON p.id = c.profile_id`
+
 func (db *DB) GetCharacter(id int) (*Character, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
 	var character Character
 
-	query := `SELECT id, notes FROM character_character WHERE id = $1`
+	query := characterSelect + ` WHERE c.id = $1`
 
 	err := db.GetContext(ctx, &character, query, id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 
-	return &character, err
+	if err != nil {
+		return nil, err
+	}
+
+	character.Effects, err = getCharacterEffects(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &character, nil
+}
+
+// InsertCharacter creates the character row. The columns Django leaves
+// blank on new characters are set to their empty values.
+func (db *DB) InsertCharacter(c *Character) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	return insertCharacter(ctx, db, c)
+}
+
+func insertCharacter(ctx context.Context, db sqlx.ExecerContext, c *Character) (int, error) {
+	query := `
+		INSERT INTO character_character (
+			name, player_id, race_id, profile_id, level, gender, age, height, weight,
+			value_strength, value_dexterity, value_constitution,
+			value_intelligence, value_wisdom, value_charisma,
+			health_max, health_remaining, armor, shield, defense_misc, initiative_misc,
+			mana_remaining, recovery_points_remaining, luck_points_remaining,
+			equipment, money_pp, money_po, money_pa, money_pc,
+			damage_reduction, notes, profile_picture, gm_notes, private
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9,
+			$10, $11, $12,
+			$13, $14, $15,
+			$16, $17, $18, $19, $20, $21,
+			$22, $23, $24,
+			$25, $26, $27, $28, $29,
+			$30, $31, $32, '', 0
+		)`
+
+	result, err := db.ExecContext(ctx, query,
+		c.Name, c.PlayerID, c.RaceID, c.ProfileID, c.Level, c.Gender, c.Age, c.Height, c.Weight,
+		c.ValueStrength, c.ValueDexterity, c.ValueConstitution,
+		c.ValueIntelligence, c.ValueWisdom, c.ValueCharisma,
+		c.HealthMax, c.HealthRemaining, c.Armor, c.Shield, c.DefenseMisc, c.InitiativeMisc,
+		c.ManaRemaining, c.RecoveryPointsRemaining, c.LuckPointsRemaining,
+		c.Equipment, c.MoneyPP, c.MoneyPO, c.MoneyPA, c.MoneyPC,
+		c.DamageReduction, c.Notes, c.ProfilePicture,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), err
+}
+
+// ImportCharacter creates the character with its capabilities and
+// inventory. Items refer to their container by the container's ID in items;
+// the inventory is marked as imported so that it is not created again from
+// the free-text equipment.
+func (db *DB) ImportCharacter(c *Character, capabilityIDs []int, items []InventoryItem) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertCharacter(ctx, tx, c)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, capabilityID := range capabilityIDs {
+		query := `INSERT INTO character_character_capabilities (character_id, capability_id) VALUES ($1, $2)`
+
+		_, err = tx.ExecContext(ctx, query, id, capabilityID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	query := `INSERT INTO inventory_imports (character_id, equipment, created) VALUES ($1, $2, $3)`
+
+	_, err = tx.ExecContext(ctx, query, id, c.Equipment, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	itemIDs := map[int]int{}
+
+	for _, item := range items {
+		localID := item.ID
+		item.CharacterID = id
+		item.ContainerID = nil
+
+		itemIDs[localID], err = insertInventoryItem(ctx, tx, &item)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, item := range items {
+		if item.ContainerID == nil {
+			continue
+		}
+
+		query := `UPDATE inventory_items SET container_id = $1 WHERE id = $2`
+
+		_, err = tx.ExecContext(ctx, query, itemIDs[*item.ContainerID], itemIDs[item.ID])
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// NotesHash identifies a version of the notes, for optimistic concurrency.
+// Notes differing only by their line endings have the same hash.
+func NotesHash(notes string) string {
+	hash := sha256.Sum256([]byte(diff.NormalizeNewlines(notes)))
+	return hex.EncodeToString(hash[:])
+}
+
+func (c *Character) NotesHash() string {
+	return NotesHash(c.Notes)
+}
+
+type Counter string
+
+const (
+	CounterHealth         Counter = "health_remaining"
+	CounterMana           Counter = "mana_remaining"
+	CounterRecoveryPoints Counter = "recovery_points_remaining"
+	CounterLuckPoints     Counter = "luck_points_remaining"
+)
+
+var Counters = []Counter{CounterHealth, CounterMana, CounterRecoveryPoints, CounterLuckPoints}
+
+func (c Counter) Valid() bool {
+	for _, counter := range Counters {
+		if c == counter {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AdjustCharacterCounter adds delta to the counter relative to its current
+// value in the database, clamped to [0, max], so that concurrent updates are
+// never lost. It returns the new value.
+func (db *DB) AdjustCharacterCounter(id int, counter Counter, delta, max int) (int, error) {
+	if !counter.Valid() {
+		return 0, fmt.Errorf("invalid counter %q", counter)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var value int
+
+	query := fmt.Sprintf(`
+		UPDATE character_character SET %[1]s = MAX(0, MIN($1, %[1]s + $2))
+		WHERE id = $3
+		RETURNING %[1]s`, counter)
+
+	err := db.GetContext(ctx, &value, query, max, delta, id)
+	return value, err
 }
 
-func (db *DB) SetCharacterNotes(id int, notes string) error {
+func (db *DB) SetCharacterCounter(id int, counter Counter, value int) error {
+	if !counter.Valid() {
+		return fmt.Errorf("invalid counter %q", counter)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	query := `UPDATE character_character SET notes = $1 WHERE id = $2`
+	query := fmt.Sprintf(`UPDATE character_character SET %s = $1 WHERE id = $2`, counter)
 
-	_, err := db.ExecContext(ctx, query, notes, id)
+	_, err := db.ExecContext(ctx, query, value, id)
 	return err
 }
+
+func (db *DB) IsCharacterGameMaster(characterID, userID int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var exists bool
+
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM party_party_characters pc
+			JOIN party_party p ON p.id = pc.party_id
+			WHERE pc.character_id = $1 AND p.game_master_id = $2
+		)`
+
+	err := db.GetContext(ctx, &exists, query, characterID, userID)
+	return exists, err
+}
+
+type CharacterSummary struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+// GetRelatedCharacters returns the characters of the same player as the
+// given character, and those it shares a party with.
+func (db *DB) GetRelatedCharacters(characterID int) ([]CharacterSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var characters []CharacterSummary
+
+	query := `
+		SELECT c.id, c.name
+		FROM character_character c
+		WHERE c.player_id = (SELECT player_id FROM character_character WHERE id = $1)
+		OR c.id IN (
+			SELECT other.character_id FROM party_party_characters own
+			JOIN party_party_characters other ON other.party_id = own.party_id
+			WHERE own.character_id = $1
+		)
+		ORDER BY c.name`
+
+	err := db.SelectContext(ctx, &characters, query, characterID)
+	return characters, err
+}
+
+func (db *DB) HasCharacterNamed(playerID int, name string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var exists bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM character_character WHERE player_id = $1 AND name = $2)`
+
+	err := db.GetContext(ctx, &exists, query, playerID, name)
+	return exists, err
+}
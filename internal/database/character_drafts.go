@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CharacterDraft holds the answers of the character creation wizard, as
+// JSON, until the character is created.
+type CharacterDraft struct {
+	UserID   int       `db:"user_id"`
+	Data     string    `db:"data"`
+	Modified time.Time `db:"modified"`
+}
+
+func (db *DB) GetCharacterDraft(userID int) (*CharacterDraft, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var draft CharacterDraft
+
+	query := `SELECT * FROM character_drafts WHERE user_id = $1`
+
+	err := db.GetContext(ctx, &draft, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &draft, err
+}
+
+func (db *DB) SaveCharacterDraft(userID int, data string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO character_drafts (user_id, data, modified) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET data = excluded.data, modified = excluded.modified`
+
+	_, err := db.ExecContext(ctx, query, userID, data, time.Now())
+	return err
+}
+
+func (db *DB) DeleteCharacterDraft(userID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `DELETE FROM character_drafts WHERE user_id = $1`
+
+	_, err := db.ExecContext(ctx, query, userID)
+	return err
+}
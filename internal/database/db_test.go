@@ -0,0 +1,75 @@
+//go:build sqlite_fts5
+
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// newTestDB returns a migrated database holding the Django tables of
+// testdata/django.sql, with one user, race and profile. The migrations need
+// FTS5, hence the build tag on the database tests.
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "db.sqlite")
+
+	schema, err := os.ReadFile("testdata/django.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	django, err := sqlx.Connect("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = django.Exec(string(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = django.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := New(dsn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// insertTestCharacter adds a level 1 character of the test user and returns
+// its ID.
+func insertTestCharacter(t *testing.T, db *DB, name, notes string) int {
+	t.Helper()
+
+	query := `
+		INSERT INTO character_character (
+			name, player_id, race_id, profile_id, level,
+			value_strength, value_dexterity, value_constitution, value_intelligence, value_wisdom, value_charisma,
+			health_max, health_remaining, armor, shield, defense_misc, initiative_misc,
+			mana_remaining, recovery_points_remaining, luck_points_remaining, notes
+		) VALUES ($1, 1, 1, 1, 1, 10, 10, 10, 10, 10, 10, 10, 10, 0, 0, 0, 0, 0, 5, 2, $2)`
+
+	result, err := db.Exec(query, name, notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return int(id)
+}
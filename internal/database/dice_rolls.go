@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type DiceRoll struct {
+	ID            int       `db:"id"`
+	CharacterID   int       `db:"character_id"`
+	CharacterName string    `db:"character_name"`
+	CampaignID    *int      `db:"campaign_id"`
+	UserID        int       `db:"user_id"`
+	Expression    string    `db:"expression"`
+	Total         int       `db:"total"`
+	Breakdown     string    `db:"breakdown"`
+	Detail        string    `db:"detail"`
+	Created       time.Time `db:"created"`
+}
+
+func (db *DB) InsertDiceRoll(roll *DiceRoll) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO dice_rolls (character_id, campaign_id, user_id, expression, total, breakdown, detail, created)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	result, err := db.ExecContext(ctx, query, roll.CharacterID, roll.CampaignID, roll.UserID, roll.Expression, roll.Total, roll.Breakdown, roll.Detail, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), err
+}
+
+// GetDiceRollHistory returns the latest rolls shared with the character: every
+// roll of its campaign, or only its own rolls when it has no campaign.
+func (db *DB) GetDiceRollHistory(characterID int, campaignID *int, limit int) ([]DiceRoll, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var rolls []DiceRoll
+
+	query := `
+		SELECT dr.*, c.name AS character_name
+		FROM dice_rolls dr
+		JOIN character_character c ON c.id = dr.character_id
+		WHERE (dr.character_id = $1 AND $2 IS NULL) OR ($2 IS NOT NULL AND dr.campaign_id = $2)
+		ORDER BY dr.created DESC, dr.id DESC
+		LIMIT $3`
+
+	err := db.SelectContext(ctx, &rolls, query, characterID, campaignID, limit)
+	return rolls, err
+}
+
+// GetCharacterCampaignID returns the campaign (Django party) the character
+// plays in. A character in several campaigns is attached to the oldest one.
+func (db *DB) GetCharacterCampaignID(characterID int) (*int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var campaignID int
+
+	query := `SELECT party_id FROM party_party_characters WHERE character_id = $1 ORDER BY party_id LIMIT 1`
+
+	err := db.GetContext(ctx, &campaignID, query, characterID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &campaignID, nil
+}
@@ -0,0 +1,297 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Effects are the conditions a character is under, e.g. stunned or blessed.
+// They modify the derived statistics until they end: expired, removed or
+// cleared by a rest. Campaign effects apply to every character of the
+// campaign but those excluded from them.
+
+type EffectDuration string
+
+const (
+	EffectRounds       EffectDuration = "rounds"
+	EffectMinutes      EffectDuration = "minutes"
+	EffectUntilRest    EffectDuration = "rest"
+	EffectUntilRemoved EffectDuration = "removed"
+)
+
+var EffectDurations = []EffectDuration{EffectRounds, EffectMinutes, EffectUntilRest, EffectUntilRemoved}
+
+const (
+	EffectExpired = "expired"
+	EffectRemoved = "removed"
+)
+
+// Effect is timed in rounds, minutes included, while RoundsRemaining is set.
+// CampaignID is only set on campaign effects.
+type Effect struct {
+	ID              int            `db:"id"`
+	CharacterID     int            `db:"character_id"`
+	CampaignID      *int           `db:"campaign_id"`
+	Name            string         `db:"name"`
+	Description     string         `db:"description"`
+	Defense         int            `db:"defense"`
+	Initiative      int            `db:"initiative"`
+	AttackMelee     int            `db:"attack_melee"`
+	AttackRanged    int            `db:"attack_ranged"`
+	AttackMagic     int            `db:"attack_magic"`
+	DurationUnit    EffectDuration `db:"duration_unit"`
+	Duration        int            `db:"duration"`
+	RoundsRemaining *int           `db:"rounds_remaining"`
+	CreatedByID     int            `db:"created_by_id"`
+	Created         time.Time      `db:"created"`
+	Ended           *time.Time     `db:"ended"`
+	EndReason       string         `db:"end_reason"`
+}
+
+const effectColumns = `
+	name, description, defense, initiative, attack_melee, attack_ranged, attack_magic,
+	duration_unit, duration, rounds_remaining, created_by_id, created, ended, end_reason`
+
+// getCharacterEffects returns the active effects of the character, those of
+// its campaigns included.
+func getCharacterEffects(ctx context.Context, db sqlx.QueryerContext, characterID int) ([]Effect, error) {
+	var effects []Effect
+
+	query := `
+		SELECT id, character_id, NULL AS campaign_id, ` + effectColumns + `
+		FROM character_effects
+		WHERE character_id = $1 AND ended IS NULL
+		UNION ALL
+		SELECT ce.id, pc.character_id, ce.campaign_id, ` + effectColumns + `
+		FROM campaign_effects ce
+		JOIN party_party_characters pc ON pc.party_id = ce.campaign_id
+		WHERE pc.character_id = $1 AND ce.ended IS NULL
+		AND NOT EXISTS(SELECT 1 FROM campaign_effect_exclusions x WHERE x.campaign_effect_id = ce.id AND x.character_id = $1)
+		ORDER BY created, id`
+
+	err := sqlx.SelectContext(ctx, db, &effects, query, characterID)
+	return effects, err
+}
+
+// GetEffectHistory returns the latest effects that ended.
+func (db *DB) GetEffectHistory(characterID, limit int) ([]Effect, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var effects []Effect
+
+	query := `SELECT * FROM character_effects WHERE character_id = $1 AND ended IS NOT NULL ORDER BY ended DESC, id DESC LIMIT $2`
+
+	err := db.SelectContext(ctx, &effects, query, characterID, limit)
+	return effects, err
+}
+
+func (db *DB) InsertEffect(effect *Effect) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO character_effects (
+			character_id, name, description, defense, initiative, attack_melee, attack_ranged, attack_magic,
+			duration_unit, duration, rounds_remaining, created_by_id, created
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	result, err := db.ExecContext(ctx, query,
+		effect.CharacterID, effect.Name, effect.Description, effect.Defense, effect.Initiative,
+		effect.AttackMelee, effect.AttackRanged, effect.AttackMagic,
+		effect.DurationUnit, effect.Duration, effect.RoundsRemaining, effect.CreatedByID, time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), err
+}
+
+func (db *DB) EndEffect(characterID, id int, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `UPDATE character_effects SET ended = $1, end_reason = $2 WHERE character_id = $3 AND id = $4 AND ended IS NULL`
+
+	_, err := db.ExecContext(ctx, query, time.Now(), reason, characterID, id)
+	return err
+}
+
+// TickEncounterEffects counts a round down on the timed effects of the
+// characters fighting in the encounter, and ends those that ran out. Effects
+// only count the rounds of the active encounter of their character or
+// campaign, the earliest one under way, so that two fights in progress do not
+// count them down twice.
+func (db *DB) TickEncounterEffects(encounterID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE character_effects SET rounds_remaining = rounds_remaining - 1
+		WHERE ended IS NULL AND rounds_remaining IS NOT NULL
+		AND $1 = (
+			SELECT e.id FROM encounters e
+			JOIN encounter_combatants ec ON ec.encounter_id = e.id
+			WHERE ec.character_id = character_effects.character_id AND e.ended IS NULL AND e.round > 0
+			ORDER BY e.created, e.id
+			LIMIT 1
+		)`
+
+	_, err = tx.ExecContext(ctx, query, encounterID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE campaign_effects SET rounds_remaining = rounds_remaining - 1
+		WHERE ended IS NULL AND rounds_remaining IS NOT NULL
+		AND campaign_id = (SELECT campaign_id FROM encounters WHERE id = $1)
+		AND $1 = (
+			SELECT e.id FROM encounters e
+			WHERE e.campaign_id = campaign_effects.campaign_id AND e.ended IS NULL AND e.round > 0
+			ORDER BY e.created, e.id
+			LIMIT 1
+		)`
+
+	_, err = tx.ExecContext(ctx, query, encounterID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, table := range []string{"character_effects", "campaign_effects"} {
+		query = `UPDATE ` + table + ` SET ended = $1, end_reason = $2 WHERE ended IS NULL AND rounds_remaining <= 0`
+
+		_, err = tx.ExecContext(ctx, query, now, EffectExpired)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetCampaignEffects returns the active effects of the campaign.
+func (db *DB) GetCampaignEffects(campaignID int) ([]Effect, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var effects []Effect
+
+	query := `SELECT * FROM campaign_effects WHERE campaign_id = $1 AND ended IS NULL ORDER BY created, id`
+
+	err := db.SelectContext(ctx, &effects, query, campaignID)
+	return effects, err
+}
+
+func (db *DB) GetCampaignEffectHistory(campaignID, limit int) ([]Effect, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var effects []Effect
+
+	query := `SELECT * FROM campaign_effects WHERE campaign_id = $1 AND ended IS NOT NULL ORDER BY ended DESC, id DESC LIMIT $2`
+
+	err := db.SelectContext(ctx, &effects, query, campaignID, limit)
+	return effects, err
+}
+
+func (db *DB) InsertCampaignEffect(campaignID int, effect *Effect) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO campaign_effects (
+			campaign_id, name, description, defense, initiative, attack_melee, attack_ranged, attack_magic,
+			duration_unit, duration, rounds_remaining, created_by_id, created
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	result, err := db.ExecContext(ctx, query,
+		campaignID, effect.Name, effect.Description, effect.Defense, effect.Initiative,
+		effect.AttackMelee, effect.AttackRanged, effect.AttackMagic,
+		effect.DurationUnit, effect.Duration, effect.RoundsRemaining, effect.CreatedByID, time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), err
+}
+
+// EndCampaignEffect ends the effect for every character of the campaign.
+func (db *DB) EndCampaignEffect(campaignID, id int, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `UPDATE campaign_effects SET ended = $1, end_reason = $2 WHERE campaign_id = $3 AND id = $4 AND ended IS NULL`
+
+	_, err := db.ExecContext(ctx, query, time.Now(), reason, campaignID, id)
+	return err
+}
+
+type CampaignEffectExclusion struct {
+	CampaignEffectID int `db:"campaign_effect_id"`
+	CharacterID      int `db:"character_id"`
+}
+
+func (db *DB) GetCampaignEffectExclusions(campaignID int) ([]CampaignEffectExclusion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var exclusions []CampaignEffectExclusion
+
+	query := `
+		SELECT x.campaign_effect_id, x.character_id
+		FROM campaign_effect_exclusions x
+		JOIN campaign_effects ce ON ce.id = x.campaign_effect_id
+		WHERE ce.campaign_id = $1`
+
+	err := db.SelectContext(ctx, &exclusions, query, campaignID)
+	return exclusions, err
+}
+
+// ExcludeFromCampaignEffect stops the effect of the campaign from applying
+// to the character.
+func (db *DB) ExcludeFromCampaignEffect(campaignID, id, characterID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT OR IGNORE INTO campaign_effect_exclusions (campaign_effect_id, character_id)
+		SELECT id, $1 FROM campaign_effects WHERE campaign_id = $2 AND id = $3`
+
+	_, err := db.ExecContext(ctx, query, characterID, campaignID, id)
+	return err
+}
+
+func (db *DB) IncludeInCampaignEffect(campaignID, id, characterID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		DELETE FROM campaign_effect_exclusions
+		WHERE character_id = $1 AND campaign_effect_id IN (SELECT id FROM campaign_effects WHERE campaign_id = $2 AND id = $3)`
+
+	_, err := db.ExecContext(ctx, query, characterID, campaignID, id)
+	return err
+}
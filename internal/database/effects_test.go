@@ -0,0 +1,96 @@
+//go:build sqlite_fts5
+
+package database
+
+import (
+	"testing"
+)
+
+func TestTickEncounterEffects(t *testing.T) {
+	db := newTestDB(t)
+
+	const campaignID = 1
+
+	characterID := insertTestCharacter(t, db, "Aldric", "")
+
+	// The character fights in two encounters of the campaign at once.
+	var encounters [2]int
+
+	for i := range encounters {
+		id, err := db.InsertEncounter(campaignID, "Embuscade")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = db.InsertCombatant(&Combatant{EncounterID: id, CharacterID: &characterID, Name: "Aldric"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = db.Exec(`UPDATE encounters SET round = 1 WHERE id = $1`, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		encounters[i] = id
+	}
+
+	rounds := 3
+	effect := &Effect{CharacterID: characterID, Name: "Béni", DurationUnit: EffectRounds, Duration: rounds, RoundsRemaining: &rounds, CreatedByID: 1}
+
+	effectID, err := db.InsertEffect(effect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	campaignEffectID, err := db.InsertCampaignEffect(campaignID, effect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remaining := func() (character, campaign int) {
+		t.Helper()
+
+		err := db.Get(&character, `SELECT rounds_remaining FROM character_effects WHERE id = $1`, effectID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.Get(&campaign, `SELECT rounds_remaining FROM campaign_effects WHERE id = $1`, campaignEffectID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return character, campaign
+	}
+
+	steps := []struct {
+		name      string
+		tick      int
+		end       bool
+		character int
+		campaign  int
+	}{
+		{name: "later encounter does not count", tick: encounters[1], character: 3, campaign: 3},
+		{name: "active encounter counts", tick: encounters[0], character: 2, campaign: 2},
+		{name: "later encounter counts once the first ended", tick: encounters[1], end: true, character: 1, campaign: 1},
+	}
+
+	for _, step := range steps {
+		if step.end {
+			err = db.EndEncounter(encounters[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err = db.TickEncounterEffects(step.tick)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if character, campaign := remaining(); character != step.character || campaign != step.campaign {
+			t.Errorf("%s: rounds remaining %d and %d, want %d and %d", step.name, character, campaign, step.character, step.campaign)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Encounters are the fights of a campaign. Round 0 means the fight has not
+// started yet; the current combatant is the one whose turn it is.
+
+type Encounter struct {
+	ID                 int        `db:"id"`
+	CampaignID         int        `db:"campaign_id"`
+	Name               string     `db:"name"`
+	Round              int        `db:"round"`
+	CurrentCombatantID *int       `db:"current_combatant_id"`
+	Ended              *time.Time `db:"ended"`
+	Created            time.Time  `db:"created"`
+	Modified           time.Time  `db:"modified"`
+}
+
+func (db *DB) GetEncounter(id int) (*Encounter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var encounter Encounter
+
+	query := `SELECT * FROM encounters WHERE id = $1`
+
+	err := db.GetContext(ctx, &encounter, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &encounter, err
+}
+
+// GetCampaignEncounters returns the encounters of the campaign, the ongoing
+// ones first.
+func (db *DB) GetCampaignEncounters(campaignID int) ([]Encounter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var encounters []Encounter
+
+	query := `SELECT * FROM encounters WHERE campaign_id = $1 ORDER BY ended IS NOT NULL, created DESC, id DESC`
+
+	err := db.SelectContext(ctx, &encounters, query, campaignID)
+	return encounters, err
+}
+
+func (db *DB) InsertEncounter(campaignID int, name string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `INSERT INTO encounters (campaign_id, name, created, modified) VALUES ($1, $2, $3, $3)`
+
+	result, err := db.ExecContext(ctx, query, campaignID, name, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), err
+}
+
+func (db *DB) EndEncounter(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `UPDATE encounters SET ended = $1, modified = $1 WHERE id = $2 AND ended IS NULL`
+
+	_, err := db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
+
+// SetEncounterTurn moves the encounter to the round and combatant, only if
+// it is still at the turn it was read at, and reports whether it moved. A
+// double click therefore advances a single turn.
+func (db *DB) SetEncounterTurn(encounter *Encounter, round int, currentCombatantID *int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE encounters SET round = $1, current_combatant_id = $2, modified = $3
+		WHERE id = $4 AND round = $5 AND current_combatant_id IS $6`
+
+	result, err := db.ExecContext(ctx, query, round, currentCombatantID, time.Now(), encounter.ID, encounter.Round, encounter.CurrentCombatantID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// Combatant is a character of the campaign or a non-player character. The
+// defense, damage reduction and health columns are only used for non-player
+// characters, whose sheet lives in the encounter.
+type Combatant struct {
+	ID              int       `db:"id"`
+	EncounterID     int       `db:"encounter_id"`
+	CharacterID     *int      `db:"character_id"`
+	Name            string    `db:"name"`
+	InitiativeBonus int       `db:"initiative_bonus"`
+	Initiative      *int      `db:"initiative"`
+	Defense         int       `db:"defense"`
+	DamageReduction int       `db:"damage_reduction"`
+	HealthMax       int       `db:"health_max"`
+	HealthRemaining int       `db:"health_remaining"`
+	Created         time.Time `db:"created"`
+}
+
+const combatantSelect = `
+	SELECT ec.id, ec.encounter_id, ec.character_id, COALESCE(c.name, ec.name) AS name, ec.initiative_bonus,
+		ec.initiative, ec.defense, ec.damage_reduction, ec.health_max, ec.health_remaining, ec.created
+	FROM encounter_combatants ec
+	LEFT JOIN character_character c ON c.id = ec.character_id`
+
+// GetCombatants returns the combatants in turn order: highest initiative
+// first, ties broken by the initiative bonus, those yet to roll last.
+func (db *DB) GetCombatants(encounterID int) ([]Combatant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var combatants []Combatant
+
+	query := combatantSelect + `
+		WHERE ec.encounter_id = $1
+		ORDER BY ec.initiative IS NULL, ec.initiative DESC, ec.initiative_bonus DESC, ec.id`
+
+	err := db.SelectContext(ctx, &combatants, query, encounterID)
+	return combatants, err
+}
+
+func (db *DB) GetCombatant(encounterID, id int) (*Combatant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var combatant Combatant
+
+	query := combatantSelect + ` WHERE ec.encounter_id = $1 AND ec.id = $2`
+
+	err := db.GetContext(ctx, &combatant, query, encounterID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &combatant, err
+}
+
+func (db *DB) InsertCombatant(combatant *Combatant) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO encounter_combatants (
+			encounter_id, character_id, name, initiative_bonus, defense, damage_reduction, health_max, health_remaining, created
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $8)`
+
+	result, err := db.ExecContext(ctx, query, combatant.EncounterID, combatant.CharacterID, combatant.Name, combatant.InitiativeBonus,
+		combatant.Defense, combatant.DamageReduction, combatant.HealthMax, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), err
+}
+
+func (db *DB) DeleteCombatant(encounterID, id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `DELETE FROM encounter_combatants WHERE encounter_id = $1 AND id = $2`
+
+	_, err := db.ExecContext(ctx, query, encounterID, id)
+	return err
+}
+
+// SetCombatantInitiative records the rolled initiative along with the bonus
+// it was rolled with, which breaks ties.
+func (db *DB) SetCombatantInitiative(id, bonus, initiative int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `UPDATE encounter_combatants SET initiative_bonus = $1, initiative = $2 WHERE id = $3`
+
+	_, err := db.ExecContext(ctx, query, bonus, initiative, id)
+	return err
+}
+
+// AdjustCombatantHealth adds delta to the health of a non-player character,
+// clamped to [0, health_max], like AdjustCharacterCounter. It returns the
+// new value.
+func (db *DB) AdjustCombatantHealth(id, delta int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var value int
+
+	query := `
+		UPDATE encounter_combatants SET health_remaining = MAX(0, MIN(health_max, health_remaining + $1))
+		WHERE id = $2
+		RETURNING health_remaining`
+
+	err := db.GetContext(ctx, &value, query, delta, id)
+	return value, err
+}
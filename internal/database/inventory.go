@@ -0,0 +1,373 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type InventoryItem struct {
+	ID          int       `db:"id"`
+	CharacterID int       `db:"character_id"`
+	Name        string    `db:"name"`
+	Quantity    int       `db:"quantity"`
+	Weight      float64   `db:"weight"`
+	ContainerID *int      `db:"container_id"`
+	IsContainer bool      `db:"is_container"`
+	Equipped    bool      `db:"equipped"`
+	Slot        string    `db:"slot"`
+	Defense     int       `db:"defense"`
+	Damage      string    `db:"damage"`
+	Created     time.Time `db:"created"`
+	Modified    time.Time `db:"modified"`
+}
+
+// Money is an amount in each coin: platinum, gold, silver and copper pieces.
+type Money struct {
+	PP int
+	PO int
+	PA int
+	PC int
+}
+
+type MoneyTransaction struct {
+	ID          int       `db:"id"`
+	CharacterID int       `db:"character_id"`
+	UserID      int       `db:"user_id"`
+	Username    *string   `db:"username"`
+	PP          int       `db:"pp"`
+	PO          int       `db:"po"`
+	PA          int       `db:"pa"`
+	PC          int       `db:"pc"`
+	Reason      string    `db:"reason"`
+	Created     time.Time `db:"created"`
+}
+
+func (db *DB) GetInventory(characterID int) ([]InventoryItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var items []InventoryItem
+
+	query := `SELECT * FROM inventory_items WHERE character_id = $1 ORDER BY is_container DESC, name`
+
+	err := db.SelectContext(ctx, &items, query, characterID)
+	return items, err
+}
+
+func (db *DB) GetInventoryItem(characterID, id int) (*InventoryItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var item InventoryItem
+
+	query := `SELECT * FROM inventory_items WHERE character_id = $1 AND id = $2`
+
+	err := db.GetContext(ctx, &item, query, characterID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &item, err
+}
+
+// GetImportedEquipment returns the free-text equipment the inventory was
+// created from, or nil if it has not been imported yet.
+func (db *DB) GetImportedEquipment(characterID int) (*string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var equipment string
+
+	query := `SELECT equipment FROM inventory_imports WHERE character_id = $1`
+
+	err := db.GetContext(ctx, &equipment, query, characterID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &equipment, err
+}
+
+// ImportEquipment creates the inventory parsed from the character's
+// free-text equipment, keeping the text. It does nothing if the inventory
+// was already imported.
+func (db *DB) ImportEquipment(characterID int, equipment string, items []InventoryItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT OR IGNORE INTO inventory_imports (character_id, equipment, created) VALUES ($1, $2, $3)`
+
+	result, err := tx.ExecContext(ctx, query, characterID, equipment, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return err
+	}
+
+	for i := range items {
+		items[i].CharacterID = characterID
+
+		_, err = insertInventoryItem(ctx, tx, &items[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func insertInventoryItem(ctx context.Context, tx *sqlx.Tx, item *InventoryItem) (int, error) {
+	query := `
+		INSERT INTO inventory_items (
+			character_id, name, quantity, weight, container_id, is_container, equipped, slot, defense, damage, created, modified
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)`
+
+	result, err := tx.ExecContext(ctx, query,
+		item.CharacterID, item.Name, item.Quantity, item.Weight, item.ContainerID,
+		item.IsContainer, item.Equipped, item.Slot, item.Defense, item.Damage, time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (db *DB) InsertInventoryItem(item *InventoryItem) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before, err := equippedDefense(ctx, tx, item.CharacterID)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := insertInventoryItem(ctx, tx, item)
+	if err != nil {
+		return 0, err
+	}
+
+	err = syncEquippedDefense(ctx, tx, item.CharacterID, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// UpdateInventoryItem saves the item.
+func (db *DB) UpdateInventoryItem(item *InventoryItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := equippedDefense(ctx, tx, item.CharacterID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE inventory_items
+		SET name = $1, quantity = $2, weight = $3, container_id = $4, is_container = $5,
+			equipped = $6, slot = $7, defense = $8, damage = $9, modified = $10
+		WHERE id = $11 AND character_id = $12`
+
+	_, err = tx.ExecContext(ctx, query,
+		item.Name, item.Quantity, item.Weight, item.ContainerID, item.IsContainer,
+		item.Equipped, item.Slot, item.Defense, item.Damage, time.Now(), item.ID, item.CharacterID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if !item.IsContainer {
+		query = `UPDATE inventory_items SET container_id = NULL WHERE container_id = $1`
+
+		_, err = tx.ExecContext(ctx, query, item.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = syncEquippedDefense(ctx, tx, item.CharacterID, before)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteInventoryItem removes the item, taking out what it contained.
+func (db *DB) DeleteInventoryItem(item *InventoryItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := equippedDefense(ctx, tx, item.CharacterID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM inventory_items WHERE id = $1 AND character_id = $2`, item.ID, item.CharacterID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE inventory_items SET container_id = NULL WHERE container_id = $1`, item.ID)
+	if err != nil {
+		return err
+	}
+
+	err = syncEquippedDefense(ctx, tx, item.CharacterID, before)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// equippedDefense returns the defense of the equipped items, by slot.
+func equippedDefense(ctx context.Context, tx *sqlx.Tx, characterID int) (map[string]int, error) {
+	var rows []struct {
+		Slot    string `db:"slot"`
+		Defense int    `db:"defense"`
+	}
+
+	query := `
+		SELECT slot, SUM(defense) AS defense FROM inventory_items
+		WHERE character_id = $1 AND equipped AND slot IN ('armor', 'shield')
+		GROUP BY slot`
+
+	err := tx.SelectContext(ctx, &rows, query, characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	defense := map[string]int{}
+	for _, row := range rows {
+		defense[row.Slot] = row.Defense
+	}
+
+	return defense, nil
+}
+
+// syncEquippedDefense adds to the character's armor and shield how much the
+// defense of the equipped items changed since before. The columns are only
+// adjusted, never overwritten, so the values set on the Django sheet are
+// kept when no equipped armor or shield changes.
+func syncEquippedDefense(ctx context.Context, tx *sqlx.Tx, characterID int, before map[string]int) error {
+	after, err := equippedDefense(ctx, tx, characterID)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range []string{"armor", "shield"} {
+		delta := after[column] - before[column]
+		if delta == 0 {
+			continue
+		}
+
+		query := `UPDATE character_character SET ` + column + ` = MAX(0, ` + column + ` + $1) WHERE id = $2`
+
+		_, err = tx.ExecContext(ctx, query, delta, characterID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateMoney replaces the character's coins and records the difference in
+// the ledger. It returns false without changing anything if the coins are
+// no longer those in before.
+func (db *DB) UpdateMoney(characterID, userID int, before, after Money, reason string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE character_character
+		SET money_pp = $1, money_po = $2, money_pa = $3, money_pc = $4
+		WHERE id = $5 AND money_pp = $6 AND money_po = $7 AND money_pa = $8 AND money_pc = $9`
+
+	result, err := tx.ExecContext(ctx, query,
+		after.PP, after.PO, after.PA, after.PC, characterID, before.PP, before.PO, before.PA, before.PC,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return false, err
+	}
+
+	query = `
+		INSERT INTO money_transactions (character_id, user_id, pp, po, pa, pc, reason, created)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = tx.ExecContext(ctx, query,
+		characterID, userID, after.PP-before.PP, after.PO-before.PO, after.PA-before.PA, after.PC-before.PC, reason, time.Now(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// GetMoneyTransactions returns the latest transactions of the character,
+// newest first.
+func (db *DB) GetMoneyTransactions(characterID, limit int) ([]MoneyTransaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var transactions []MoneyTransaction
+
+	query := `
+		SELECT mt.*, u.username
+		FROM money_transactions mt
+		LEFT JOIN common_user u ON u.id = mt.user_id
+		WHERE mt.character_id = $1
+		ORDER BY mt.id DESC
+		LIMIT $2`
+
+	err := db.SelectContext(ctx, &transactions, query, characterID, limit)
+	return transactions, err
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// LevelUp records the changes made to a character when it gained a level.
+// The capability name is kept so that the entry survives catalog changes.
+type LevelUp struct {
+	ID             int       `db:"id"`
+	CharacterID    int       `db:"character_id"`
+	UserID         int       `db:"user_id"`
+	Username       *string   `db:"username"`
+	Level          int       `db:"level"`
+	CapabilityID   *int      `db:"capability_id"`
+	CapabilityName string    `db:"capability_name"`
+	HealthMethod   string    `db:"health_method"`
+	HitDie         int       `db:"hit_die"`
+	HealthGain     int       `db:"health_gain"`
+	ManaGain       int       `db:"mana_gain"`
+	Created        time.Time `db:"created"`
+}
+
+// LevelUpCharacter applies the level up and records it. It returns false
+// without changing anything if the character is no longer at the level
+// before lu.Level, e.g. after a concurrent level up.
+func (db *DB) LevelUpCharacter(lu *LevelUp, manaMax int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE character_character
+		SET level = $1, health_max = health_max + $2, health_remaining = health_remaining + $2,
+			mana_remaining = MIN($3, MAX(0, mana_remaining + $4))
+		WHERE id = $5 AND level = $6`
+
+	result, err := tx.ExecContext(ctx, query, lu.Level, lu.HealthGain, manaMax, lu.ManaGain, lu.CharacterID, lu.Level-1)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return false, err
+	}
+
+	if lu.CapabilityID != nil {
+		query = `INSERT INTO character_character_capabilities (character_id, capability_id) VALUES ($1, $2)`
+
+		_, err = tx.ExecContext(ctx, query, lu.CharacterID, *lu.CapabilityID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	query = `
+		INSERT INTO level_ups (
+			character_id, user_id, level, capability_id, capability_name,
+			health_method, hit_die, health_gain, mana_gain, created
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err = tx.ExecContext(ctx, query,
+		lu.CharacterID, lu.UserID, lu.Level, lu.CapabilityID, lu.CapabilityName,
+		lu.HealthMethod, lu.HitDie, lu.HealthGain, lu.ManaGain, time.Now(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	query = `DELETE FROM level_up_rolls WHERE character_id = $1 AND level <= $2`
+
+	_, err = tx.ExecContext(ctx, query, lu.CharacterID, lu.Level)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// KeepLevelUpRoll stores roll as the hit die rolled for the character's next
+// level, unless one was stored already, and returns the stored one. The roll
+// is deleted once the level up is applied.
+func (db *DB) KeepLevelUpRoll(characterID, level, roll int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT OR IGNORE INTO level_up_rolls (character_id, level, hit_die, created)
+		VALUES ($1, $2, $3, $4)`
+
+	_, err := db.ExecContext(ctx, query, characterID, level, roll, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	query = `SELECT hit_die FROM level_up_rolls WHERE character_id = $1 AND level = $2`
+
+	err = db.GetContext(ctx, &roll, query, characterID, level)
+	return roll, err
+}
+
+// GetLevelUps returns the level ups of the character, newest first.
+func (db *DB) GetLevelUps(characterID int) ([]LevelUp, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var levelUps []LevelUp
+
+	query := `
+		SELECT lu.*, u.username
+		FROM level_ups lu
+		LEFT JOIN common_user u ON u.id = lu.user_id
+		WHERE lu.character_id = $1
+		ORDER BY lu.id DESC`
+
+	err := db.SelectContext(ctx, &levelUps, query, characterID)
+	return levelUps, err
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Crocmagnon/charasheet-go/internal/diff"
+	"github.com/jmoiron/sqlx"
+)
+
+type NotesRevision struct {
+	ID          int       `db:"id"`
+	CharacterID int       `db:"character_id"`
+	UserID      *int      `db:"user_id"`
+	Username    *string   `db:"username"`
+	Notes       string    `db:"notes"`
+	Created     time.Time `db:"created"`
+}
+
+// SaveCharacterNotes writes the notes of the character edited by the user
+// and records them as a revision, keeping at most keep revisions. The notes
+// are only written if they still equal expected; it reports whether they
+// were. Line endings are normalized before comparing and writing, as Django
+// saves notes with "\r\n". Saving unchanged notes records no revision.
+func (db *DB) SaveCharacterNotes(characterID, userID int, expected, notes string, keep int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	expected = diff.NormalizeNewlines(expected)
+	notes = diff.NormalizeNewlines(notes)
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE character_character SET notes = $1
+		WHERE id = $2 AND REPLACE(REPLACE(notes, char(13) || char(10), char(10)), char(13), char(10)) = $3`
+
+	result, err := tx.ExecContext(ctx, query, notes, characterID, expected)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return false, err
+	}
+
+	if expected != notes {
+		err = insertNotesRevision(ctx, tx, characterID, userID, expected, notes, keep)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// insertNotesRevision records notes saved by the user, keeping at most keep
+// revisions for the character. The notes they replaced are recorded first
+// when the character has no history yet, so that the first edit can be
+// undone too.
+func insertNotesRevision(ctx context.Context, tx *sqlx.Tx, characterID, userID int, previous, notes string, keep int) error {
+	var count int
+
+	err := tx.GetContext(ctx, &count, `SELECT COUNT(*) FROM notes_revisions WHERE character_id = $1`, characterID)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO notes_revisions (character_id, user_id, notes, created) VALUES ($1, $2, $3, $4)`
+
+	if count == 0 && previous != "" {
+		_, err = tx.ExecContext(ctx, query, characterID, nil, previous, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, query, characterID, userID, notes, time.Now())
+	if err != nil {
+		return err
+	}
+
+	query = `
+		DELETE FROM notes_revisions
+		WHERE character_id = $1 AND id NOT IN (
+			SELECT id FROM notes_revisions WHERE character_id = $1 ORDER BY id DESC LIMIT $2
+		)`
+
+	_, err = tx.ExecContext(ctx, query, characterID, keep)
+	return err
+}
+
+// GetNotesRevisions returns the revisions of the character's notes, newest
+// first.
+func (db *DB) GetNotesRevisions(characterID int) ([]NotesRevision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var revisions []NotesRevision
+
+	query := `
+		SELECT nr.*, u.username
+		FROM notes_revisions nr
+		LEFT JOIN common_user u ON u.id = nr.user_id
+		WHERE nr.character_id = $1
+		ORDER BY nr.id DESC`
+
+	err := db.SelectContext(ctx, &revisions, query, characterID)
+	return revisions, err
+}
+
+func (db *DB) GetNotesRevision(characterID, id int) (*NotesRevision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var revision NotesRevision
+
+	query := `
+		SELECT nr.*, u.username
+		FROM notes_revisions nr
+		LEFT JOIN common_user u ON u.id = nr.user_id
+		WHERE nr.character_id = $1 AND nr.id = $2`
+
+	err := db.GetContext(ctx, &revision, query, characterID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &revision, err
+}
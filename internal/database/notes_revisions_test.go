@@ -0,0 +1,75 @@
+//go:build sqlite_fts5
+
+package database
+
+import (
+	"testing"
+)
+
+func TestSaveCharacterNotesWindowsLineEndings(t *testing.T) {
+	db := newTestDB(t)
+
+	// Django saves the notes as the textarea submitted them.
+	stored := "# Session 1\r\nOn a rencontré Gérard.\r\n"
+	id := insertTestCharacter(t, db, "Aldric", stored)
+
+	// The browser drops the "\r" from the hidden base notes.
+	base := "# Session 1\nOn a rencontré Gérard.\n"
+
+	if NotesHash(base) != NotesHash(stored) {
+		t.Fatalf("NotesHash(%q) != NotesHash(%q)", base, stored)
+	}
+
+	saved, err := db.SaveCharacterNotes(id, 1, base, base+"Puis Aldric.\r\n", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !saved {
+		t.Fatal("SaveCharacterNotes() = false, want true")
+	}
+
+	var notes string
+
+	err = db.Get(&notes, `SELECT notes FROM character_character WHERE id = $1`, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := base + "Puis Aldric.\n"; notes != want {
+		t.Errorf("notes = %q, want %q", notes, want)
+	}
+
+	revisions, err := db.GetNotesRevisions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(revisions) != 2 {
+		t.Fatalf("len(GetNotesRevisions()) = %d, want 2", len(revisions))
+	}
+}
+
+func TestSaveCharacterNotesStale(t *testing.T) {
+	db := newTestDB(t)
+
+	id := insertTestCharacter(t, db, "Aldric", "a\r\nb\r\n")
+
+	saved, err := db.SaveCharacterNotes(id, 1, "a\n", "a\nc\n", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if saved {
+		t.Error("SaveCharacterNotes() = true with stale notes, want false")
+	}
+
+	revisions, err := db.GetNotesRevisions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(revisions) != 0 {
+		t.Errorf("len(GetNotesRevisions()) = %d, want 0", len(revisions))
+	}
+}
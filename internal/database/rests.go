@@ -0,0 +1,265 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Rests record the counters of the characters before and after they rested,
+// and the effects the rest ended, so that the rest can be undone. A campaign
+// rest is taken by all the characters of the campaign at once.
+
+const EffectRested = "rest"
+
+type Rest struct {
+	ID         int             `db:"id"`
+	CampaignID *int            `db:"campaign_id"`
+	UserID     int             `db:"user_id"`
+	Kind       string          `db:"kind"`
+	Created    time.Time       `db:"created"`
+	Undone     *time.Time      `db:"undone"`
+	Characters []RestCharacter `db:"-"`
+}
+
+// RestCharacter holds the gains of the character as after values, applied
+// relative to the counters in the database and clamped to the maxima.
+type RestCharacter struct {
+	RestID         int    `db:"rest_id"`
+	CharacterID    int    `db:"character_id"`
+	CharacterName  string `db:"character_name"`
+	HealthBefore   int    `db:"health_before"`
+	HealthAfter    int    `db:"health_after"`
+	ManaBefore     int    `db:"mana_before"`
+	ManaAfter      int    `db:"mana_after"`
+	RecoveryBefore int    `db:"recovery_before"`
+	RecoveryAfter  int    `db:"recovery_after"`
+	Rolls          string `db:"rolls"`
+	Effects        string `db:"effects"`
+
+	HealthMax   int `db:"-"`
+	ManaMax     int `db:"-"`
+	RecoveryMax int `db:"-"`
+}
+
+// InsertRest applies the rest to every character in a single transaction,
+// and ends the effects lasting until a rest. A campaign rest ends those of the
+// campaign; a character resting alone is excluded from them instead.
+func (db *DB) InsertRest(rest *Rest) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	query := `INSERT INTO rests (campaign_id, user_id, kind, created) VALUES ($1, $2, $3, $4)`
+
+	result, err := tx.ExecContext(ctx, query, rest.CampaignID, rest.UserID, rest.Kind, now)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range rest.Characters {
+		rc := &rest.Characters[i]
+		rc.RestID = int(id)
+
+		query = `
+			UPDATE character_character SET
+				health_remaining = MAX(0, MIN($1, health_remaining + $2)),
+				mana_remaining = MAX(0, MIN($3, mana_remaining + $4)),
+				recovery_points_remaining = MAX(0, MIN($5, recovery_points_remaining + $6))
+			WHERE id = $7
+			RETURNING health_remaining, mana_remaining, recovery_points_remaining`
+
+		err = tx.QueryRowxContext(ctx, query,
+			rc.HealthMax, rc.HealthAfter-rc.HealthBefore,
+			rc.ManaMax, rc.ManaAfter-rc.ManaBefore,
+			rc.RecoveryMax, rc.RecoveryAfter-rc.RecoveryBefore,
+			rc.CharacterID,
+		).Scan(&rc.HealthAfter, &rc.ManaAfter, &rc.RecoveryAfter)
+		if err != nil {
+			return 0, err
+		}
+
+		query = `
+			INSERT INTO rest_characters (
+				rest_id, character_id, health_before, health_after, mana_before, mana_after,
+				recovery_before, recovery_after, rolls, effects
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+		_, err = tx.ExecContext(ctx, query, id, rc.CharacterID, rc.HealthBefore, rc.HealthAfter, rc.ManaBefore, rc.ManaAfter,
+			rc.RecoveryBefore, rc.RecoveryAfter, rc.Rolls, rc.Effects)
+		if err != nil {
+			return 0, err
+		}
+
+		query = `
+			INSERT INTO rest_effects (rest_id, character_effect_id)
+			SELECT $1, id FROM character_effects WHERE character_id = $2 AND ended IS NULL AND duration_unit = $3`
+
+		_, err = tx.ExecContext(ctx, query, id, rc.CharacterID, EffectUntilRest)
+		if err != nil {
+			return 0, err
+		}
+
+		query = `
+			UPDATE character_effects SET ended = $1, end_reason = $2
+			WHERE character_id = $3 AND ended IS NULL AND duration_unit = $4`
+
+		_, err = tx.ExecContext(ctx, query, now, EffectRested, rc.CharacterID, EffectUntilRest)
+		if err != nil {
+			return 0, err
+		}
+
+		if rest.CampaignID != nil {
+			continue
+		}
+
+		query = `
+			INSERT INTO rest_effects (rest_id, campaign_effect_id, excluded_character_id)
+			SELECT $1, ce.id, pc.character_id
+			FROM campaign_effects ce
+			JOIN party_party_characters pc ON pc.party_id = ce.campaign_id
+			WHERE pc.character_id = $2 AND ce.ended IS NULL AND ce.duration_unit = $3
+			AND NOT EXISTS(SELECT 1 FROM campaign_effect_exclusions x WHERE x.campaign_effect_id = ce.id AND x.character_id = $2)`
+
+		_, err = tx.ExecContext(ctx, query, id, rc.CharacterID, EffectUntilRest)
+		if err != nil {
+			return 0, err
+		}
+
+		query = `
+			INSERT INTO campaign_effect_exclusions (campaign_effect_id, character_id)
+			SELECT campaign_effect_id, excluded_character_id FROM rest_effects WHERE rest_id = $1 AND excluded_character_id = $2`
+
+		_, err = tx.ExecContext(ctx, query, id, rc.CharacterID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if rest.CampaignID != nil {
+		query = `
+			INSERT INTO rest_effects (rest_id, campaign_effect_id)
+			SELECT $1, id FROM campaign_effects WHERE campaign_id = $2 AND ended IS NULL AND duration_unit = $3`
+
+		_, err = tx.ExecContext(ctx, query, id, *rest.CampaignID, EffectUntilRest)
+		if err != nil {
+			return 0, err
+		}
+
+		query = `
+			UPDATE campaign_effects SET ended = $1, end_reason = $2
+			WHERE campaign_id = $3 AND ended IS NULL AND duration_unit = $4`
+
+		_, err = tx.ExecContext(ctx, query, now, EffectRested, *rest.CampaignID, EffectUntilRest)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int(id), tx.Commit()
+}
+
+func (db *DB) GetRest(id int) (*Rest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var rest Rest
+
+	query := `SELECT * FROM rests WHERE id = $1`
+
+	err := db.GetContext(ctx, &rest, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT rc.*, c.name AS character_name
+		FROM rest_characters rc
+		JOIN character_character c ON c.id = rc.character_id
+		WHERE rc.rest_id = $1
+		ORDER BY c.name`
+
+	err = db.SelectContext(ctx, &rest.Characters, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rest, nil
+}
+
+// UndoRest takes back what the rest changed, if it was taken after since and
+// not undone yet, and reports whether it was undone. The counters are changed
+// relative to their current values, keeping changes made since the rest.
+func (db *DB) UndoRest(rest *Rest, since time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE rests SET undone = $1 WHERE id = $2 AND undone IS NULL AND created > $3`
+
+	result, err := tx.ExecContext(ctx, query, time.Now(), rest.ID, since)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return false, err
+	}
+
+	for _, rc := range rest.Characters {
+		query = `
+			UPDATE character_character SET
+				health_remaining = MAX(0, health_remaining - $1),
+				mana_remaining = MAX(0, mana_remaining - $2),
+				recovery_points_remaining = MAX(0, recovery_points_remaining - $3)
+			WHERE id = $4`
+
+		_, err = tx.ExecContext(ctx, query, rc.HealthAfter-rc.HealthBefore, rc.ManaAfter-rc.ManaBefore, rc.RecoveryAfter-rc.RecoveryBefore, rc.CharacterID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	queries := []string{
+		`UPDATE character_effects SET ended = NULL, end_reason = ''
+		WHERE id IN (SELECT character_effect_id FROM rest_effects WHERE rest_id = $1)`,
+		`UPDATE campaign_effects SET ended = NULL, end_reason = ''
+		WHERE id IN (SELECT campaign_effect_id FROM rest_effects WHERE rest_id = $1 AND excluded_character_id IS NULL)`,
+		`DELETE FROM campaign_effect_exclusions
+		WHERE (campaign_effect_id, character_id) IN (
+			SELECT campaign_effect_id, excluded_character_id FROM rest_effects WHERE rest_id = $1 AND excluded_character_id IS NOT NULL
+		)`,
+	}
+
+	for _, query := range queries {
+		_, err = tx.ExecContext(ctx, query, rest.ID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
@@ -0,0 +1,89 @@
+//go:build sqlite_fts5
+
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUndoRest(t *testing.T) {
+	db := newTestDB(t)
+
+	id := insertTestCharacter(t, db, "Aldric", "")
+
+	rest := &Rest{
+		UserID: 1,
+		Kind:   "short",
+		Characters: []RestCharacter{{
+			CharacterID:  id,
+			HealthBefore: 10, HealthAfter: 15, HealthMax: 20,
+			ManaBefore: 0, ManaAfter: 0,
+			RecoveryBefore: 5, RecoveryAfter: 4, RecoveryMax: 5,
+			Rolls: "5",
+		}},
+	}
+
+	// The character has 10 health points out of 20 and 5 recovery points.
+	_, err := db.Exec(`UPDATE character_character SET health_max = 20 WHERE id = $1`, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().Add(-time.Minute)
+
+	rest.ID, err = db.InsertRest(rest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counters := func() (health, recovery int) {
+		t.Helper()
+
+		err := db.QueryRow(`SELECT health_remaining, recovery_points_remaining FROM character_character WHERE id = $1`, id).Scan(&health, &recovery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return health, recovery
+	}
+
+	if health, recovery := counters(); health != 15 || recovery != 4 {
+		t.Fatalf("after the rest: health %d, recovery %d, want 15, 4", health, recovery)
+	}
+
+	undone, err := db.UndoRest(rest, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if undone {
+		t.Error("UndoRest() = true for a rest taken before since, want false")
+	}
+
+	if health, recovery := counters(); health != 15 || recovery != 4 {
+		t.Errorf("after a refused undo: health %d, recovery %d, want 15, 4", health, recovery)
+	}
+
+	undone, err = db.UndoRest(rest, before)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !undone {
+		t.Fatal("UndoRest() = false within the window, want true")
+	}
+
+	if health, recovery := counters(); health != 10 || recovery != 5 {
+		t.Errorf("after the undo: health %d, recovery %d, want 10, 5", health, recovery)
+	}
+
+	undone, err = db.UndoRest(rest, before)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if undone {
+		t.Error("UndoRest() = true for a rest already undone, want false")
+	}
+}
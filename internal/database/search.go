@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"strings"
+)
+
+// Search snippets mark matches with these control characters, so that the
+// text can be escaped before the marks are turned into HTML.
+const (
+	SearchMatchStart = "\x02"
+	SearchMatchEnd   = "\x03"
+)
+
+type CharacterSearchResult struct {
+	ID           int    `db:"id"`
+	PlayerID     int    `db:"player_id"`
+	PlayerName   string `db:"player_name"`
+	Name         string `db:"name"`
+	Snippet      string `db:"snippet"`
+	IsPlayer     bool   `db:"is_player"`
+	IsGameMaster bool   `db:"is_game_master"`
+}
+
+// SearchCharacters looks for the terms of input in the names and notes of the
+// characters userID may read the notes of, either as their player or as the
+// game master of one of their parties. Staff members search every character.
+func (db *DB) SearchCharacters(input string, userID int, isStaff bool, limit int) ([]CharacterSearchResult, error) {
+	match := searchMatchQuery(input)
+	if match == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var results []CharacterSearchResult
+
+	query := `
+		SELECT id, player_id, player_name, name, snippet, is_player, is_game_master FROM (
+			SELECT
+				c.id,
+				c.player_id,
+				u.username AS player_name,
+				highlight(search_characters, 0, $1, $2) AS name,
+				snippet(search_characters, 1, $1, $2, '…', 16) AS snippet,
+				c.player_id = $3 AS is_player,
+				EXISTS(
+					SELECT 1 FROM party_party_characters pc
+					JOIN party_party p ON p.id = pc.party_id
+					WHERE pc.character_id = c.id AND p.game_master_id = $3
+				) AS is_game_master,
+				search_characters.rank AS rank
+			FROM search_characters
+			JOIN character_character c ON c.id = search_characters.rowid
+			JOIN common_user u ON u.id = c.player_id
+			WHERE search_characters MATCH $4
+		)
+		WHERE $5 OR is_player OR is_game_master
+		ORDER BY rank
+		LIMIT $6`
+
+	err := db.SelectContext(ctx, &results, query, SearchMatchStart, SearchMatchEnd, userID, match, isStaff, limit)
+	return results, err
+}
+
+// searchMatchQuery turns user input into an FTS5 query matching every word,
+// the last one as a prefix. Words are quoted, so FTS5 operators in the input
+// are searched literally.
+func searchMatchQuery(input string) string {
+	words := strings.Fields(input)
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+	}
+
+	if len(terms) == 0 {
+		return ""
+	}
+
+	terms[len(terms)-1] += "*"
+
+	return strings.Join(terms, " ")
+}
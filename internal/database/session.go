@@ -1,69 +1,94 @@
 package database
 
 import (
-	"bytes"
-	"compress/zlib"
 	"context"
 	"database/sql"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"strings"
 	"time"
+
+	"github.com/Crocmagnon/charasheet-go/internal/django"
 )
 
+// djangoDateTimeFormat is how Django's sqlite backend stores UTC datetimes.
+const djangoDateTimeFormat = "2006-01-02 15:04:05.000000"
+
 type DjangoSession struct {
 	SessionKey  string    `db:"session_key"`
 	SessionData string    `db:"session_data"`
 	ExpireData  time.Time `db:"expire_date"`
 }
 
-func (s *DjangoSession) Decode() (*DjangoSessionData, error) {
-	split := strings.Split(s.SessionData, ":")
-	value := split[0]
-	isCompressed := value[0] == '.'
-
-	if isCompressed {
-		value = value[1:]
-	}
+func (s *DjangoSession) Decode(signer *django.Signer) (*DjangoSessionData, error) {
+	var session DjangoSessionData
 
-	data, err := base64.RawURLEncoding.DecodeString(value)
+	// As in Django's database backend, sessions expire through their
+	// expire_date rather than the age of the signature.
+	err := signer.UnsignObject(s.SessionData, 0, &session)
 	if err != nil {
-		return nil, fmt.Errorf("decoding base64: %w", err)
+		return nil, err
 	}
 
-	var session DjangoSessionData
+	return &session, nil
+}
 
-	if !isCompressed {
-		err = json.Unmarshal(data, &session)
-		if err != nil {
-			return nil, fmt.Errorf("unmarshalling json: %w", err)
-		}
+type DjangoSessionData struct {
+	Preview         bool   `json:"preview,omitempty"`
+	AuthUserID      string `json:"_auth_user_id"`
+	AuthUserBackend string `json:"_auth_user_backend"`
+	AuthUserHash    string `json:"_auth_user_hash"`
 
-		return &session, nil
+	// Extra holds the keys the Django app stored that are not read here, so
+	// that they survive when the session is written back.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// djangoSessionFields has the fields of DjangoSessionData without its JSON
+// methods.
+type djangoSessionFields DjangoSessionData
+
+func (d *DjangoSessionData) UnmarshalJSON(data []byte) error {
+	var fields djangoSessionFields
+
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
 	}
 
-	decompressed, err := zlib.NewReader(bytes.NewReader(data))
+	err = json.Unmarshal(data, &fields.Extra)
 	if err != nil {
-		return nil, fmt.Errorf("decompressing data: %w", err)
+		return err
+	}
+
+	for _, key := range []string{"preview", "_auth_user_id", "_auth_user_backend", "_auth_user_hash"} {
+		delete(fields.Extra, key)
 	}
 
-	defer decompressed.Close()
+	*d = DjangoSessionData(fields)
 
-	err = json.NewDecoder(decompressed).Decode(&session)
+	return nil
+}
+
+func (d DjangoSessionData) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(djangoSessionFields(d))
+	if err != nil || len(d.Extra) == 0 {
+		return data, err
+	}
+
+	var merged map[string]json.RawMessage
+
+	err = json.Unmarshal(data, &merged)
 	if err != nil {
-		return nil, fmt.Errorf("decoding json: %w", err)
+		return nil, err
 	}
 
-	return &session, nil
-}
+	for key, value := range d.Extra {
+		if _, ok := merged[key]; !ok {
+			merged[key] = value
+		}
+	}
 
-type DjangoSessionData struct {
-	Preview         bool   `json:"preview"`
-	AuthUserID      string `json:"_auth_user_id"`
-	AuthUserBackend string `json:"_auth_user_backend"`
-	AuthUserHash    string `json:"_auth_user_hash"`
+	return json.Marshal(merged)
 }
 
 func (db *DB) GetSession(key string) (*DjangoSession, error) {
@@ -81,3 +106,37 @@ func (db *DB) GetSession(key string) (*DjangoSession, error) {
 
 	return &session, err
 }
+
+func (db *DB) GetSessions() ([]DjangoSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var sessions []DjangoSession
+
+	query := `SELECT * FROM django_session WHERE expire_date >= datetime('now')`
+
+	err := db.SelectContext(ctx, &sessions, query)
+	return sessions, err
+}
+
+func (db *DB) InsertSession(key, data string, expiry time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO django_session (session_key, session_data, expire_date)
+		VALUES ($1, $2, $3)`
+
+	_, err := db.ExecContext(ctx, query, key, data, expiry.UTC().Format(djangoDateTimeFormat))
+	return err
+}
+
+func (db *DB) DeleteSession(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `DELETE FROM django_session WHERE session_key = $1`
+
+	_, err := db.ExecContext(ctx, query, key)
+	return err
+}
@@ -0,0 +1,139 @@
+-- The tables owned by the Django app that the Go migrations and queries
+-- rely on, reduced to the columns they use.
+
+CREATE TABLE common_user (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    password VARCHAR(128) NOT NULL,
+    last_login DATETIME,
+    is_superuser BOOL NOT NULL,
+    username VARCHAR(150) NOT NULL UNIQUE,
+    first_name VARCHAR(150) NOT NULL,
+    last_name VARCHAR(150) NOT NULL,
+    email VARCHAR(254) NOT NULL,
+    is_staff BOOL NOT NULL,
+    is_active BOOL NOT NULL,
+    date_joined DATETIME NOT NULL
+);
+
+CREATE TABLE django_session (
+    session_key VARCHAR(40) NOT NULL PRIMARY KEY,
+    session_data TEXT NOT NULL,
+    expire_date DATETIME NOT NULL
+);
+
+CREATE TABLE character_race (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL
+);
+
+CREATE TABLE character_profile (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    life_dice INTEGER NOT NULL,
+    magical_strength VARCHAR(3) NOT NULL,
+    mana_max_compute INTEGER NOT NULL
+);
+
+CREATE TABLE character_character (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    player_id INTEGER NOT NULL,
+    race_id INTEGER NOT NULL,
+    profile_id INTEGER NOT NULL,
+    level INTEGER NOT NULL,
+    gender VARCHAR(1) NOT NULL DEFAULT 'M',
+    age INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0,
+    weight INTEGER NOT NULL DEFAULT 0,
+    value_strength INTEGER NOT NULL,
+    value_dexterity INTEGER NOT NULL,
+    value_constitution INTEGER NOT NULL,
+    value_intelligence INTEGER NOT NULL,
+    value_wisdom INTEGER NOT NULL,
+    value_charisma INTEGER NOT NULL,
+    health_max INTEGER NOT NULL,
+    health_remaining INTEGER NOT NULL,
+    armor INTEGER NOT NULL,
+    shield INTEGER NOT NULL,
+    defense_misc INTEGER NOT NULL,
+    initiative_misc INTEGER NOT NULL,
+    mana_remaining INTEGER NOT NULL,
+    recovery_points_remaining INTEGER NOT NULL,
+    luck_points_remaining INTEGER NOT NULL,
+    equipment TEXT NOT NULL DEFAULT '',
+    money_pp INTEGER NOT NULL DEFAULT 0,
+    money_po INTEGER NOT NULL DEFAULT 0,
+    money_pa INTEGER NOT NULL DEFAULT 0,
+    money_pc INTEGER NOT NULL DEFAULT 0,
+    damage_reduction TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    gm_notes TEXT NOT NULL DEFAULT '',
+    private BOOL NOT NULL DEFAULT 0,
+    profile_picture VARCHAR(100) NOT NULL DEFAULT '',
+    racial_capability_id INTEGER
+);
+
+CREATE TABLE character_path (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    category VARCHAR(20) NOT NULL,
+    profile_id INTEGER,
+    race_id INTEGER,
+    notes TEXT NOT NULL DEFAULT '',
+    url VARCHAR(200) NOT NULL DEFAULT '',
+    created DATETIME NOT NULL,
+    modified DATETIME NOT NULL
+);
+
+CREATE TABLE character_capability (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    path_id INTEGER NOT NULL,
+    rank INTEGER NOT NULL,
+    limited BOOL NOT NULL DEFAULT 0,
+    spell BOOL NOT NULL DEFAULT 0,
+    description TEXT NOT NULL DEFAULT '',
+    url VARCHAR(200) NOT NULL DEFAULT '',
+    created DATETIME NOT NULL,
+    modified DATETIME NOT NULL
+);
+
+CREATE TABLE character_character_capabilities (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    character_id INTEGER NOT NULL,
+    capability_id INTEGER NOT NULL,
+    UNIQUE (character_id, capability_id)
+);
+
+CREATE TABLE party_party (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    game_master_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    modified DATETIME NOT NULL
+);
+
+CREATE TABLE party_party_members (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    party_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL
+);
+
+CREATE TABLE party_party_characters (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    party_id INTEGER NOT NULL,
+    character_id INTEGER NOT NULL
+);
+
+CREATE TABLE party_party_invited_characters (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    party_id INTEGER NOT NULL,
+    character_id INTEGER NOT NULL
+);
+
+INSERT INTO common_user (password, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined)
+VALUES ('!', 0, 'player', '', '', 'player@example.com', 0, 1, '2024-01-01 00:00:00');
+
+INSERT INTO character_race (name) VALUES ('Humain');
+
+INSERT INTO character_profile (name, life_dice, magical_strength, mana_max_compute) VALUES ('Guerrier', 10, 'NON', 0);
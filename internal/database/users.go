@@ -12,6 +12,8 @@ type User struct {
 	Created        time.Time `db:"date_joined"`
 	Email          string    `db:"email"`
 	HashedPassword string    `db:"password"`
+	IsStaff        bool      `db:"is_staff"`
+	IsSuperuser    bool      `db:"is_superuser"`
 }
 
 func (db *DB) InsertUser(email, hashedPassword string) (int, error) {
@@ -41,7 +43,7 @@ func (db *DB) GetUser(id int) (*User, error) {
 
 	var user User
 
-	query := `SELECT id, date_joined, email, password FROM common_user WHERE id = $1`
+	query := `SELECT id, date_joined, email, password, is_staff, is_superuser FROM common_user WHERE id = $1`
 
 	err := db.GetContext(ctx, &user, query, id)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -57,7 +59,7 @@ func (db *DB) GetUserByEmail(email string) (*User, error) {
 
 	var user User
 
-	query := `SELECT id, date_joined, email, password FROM common_user WHERE email = $1`
+	query := `SELECT id, date_joined, email, password, is_staff, is_superuser FROM common_user WHERE email = $1`
 
 	err := db.GetContext(ctx, &user, query, email)
 	if errors.Is(err, sql.ErrNoRows) {
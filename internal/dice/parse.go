@@ -0,0 +1,273 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxDice  = 100
+	MaxSides = 1000
+	maxTerms = 20
+)
+
+var ErrInvalidExpression = errors.New("invalid dice expression")
+
+type TermKind string
+
+const (
+	TermDice      TermKind = "dice"
+	TermConstant  TermKind = "constant"
+	TermReference TermKind = "reference"
+)
+
+type KeepMode string
+
+const (
+	KeepAll     KeepMode = ""
+	KeepHighest KeepMode = "kh"
+	KeepLowest  KeepMode = "kl"
+)
+
+// Term is one signed operand of an expression: a group of dice such as
+// "2d20kh1" or "3d6!", a constant, or a reference such as "@FOR".
+type Term struct {
+	Sign      int      `json:"sign"`
+	Kind      TermKind `json:"kind"`
+	Count     int      `json:"count,omitempty"`
+	Sides     int      `json:"sides,omitempty"`
+	Keep      KeepMode `json:"keep,omitempty"`
+	KeepCount int      `json:"keep_count,omitempty"`
+	Explode   bool     `json:"explode,omitempty"`
+	Value     int      `json:"value,omitempty"`
+	Reference string   `json:"reference,omitempty"`
+}
+
+func (t Term) String() string {
+	switch t.Kind {
+	case TermDice:
+		s := fmt.Sprintf("%dd%d", t.Count, t.Sides)
+		if t.Explode {
+			s += "!"
+		}
+
+		if t.Keep != KeepAll {
+			s += fmt.Sprintf("%s%d", t.Keep, t.KeepCount)
+		}
+
+		return s
+	case TermReference:
+		return "@" + t.Reference
+	}
+
+	return strconv.Itoa(t.Value)
+}
+
+type Expression struct {
+	Terms []Term `json:"terms"`
+}
+
+func (e *Expression) String() string {
+	var sb strings.Builder
+
+	for i, term := range e.Terms {
+		switch {
+		case term.Sign < 0:
+			sb.WriteString("-")
+		case i > 0:
+			sb.WriteString("+")
+		}
+
+		sb.WriteString(term.String())
+	}
+
+	return sb.String()
+}
+
+// Parse reads expressions such as "2d6+3", "1d20+@FOR", "2d20kh1", "4d6kh3"
+// or "3d6!" (exploding dice). A missing dice count defaults to one ("d20").
+func Parse(input string) (*Expression, error) {
+	p := &parser{input: input}
+
+	if p.skipSpaces(); p.done() {
+		return nil, fmt.Errorf("%w: empty expression", ErrInvalidExpression)
+	}
+
+	expression := &Expression{}
+
+	for !p.done() {
+		sign := 1
+
+		switch {
+		case p.accept("+"):
+			if len(expression.Terms) == 0 {
+				return nil, p.errorf("unexpected '+'")
+			}
+		case p.accept("-"):
+			sign = -1
+		case len(expression.Terms) > 0:
+			return nil, p.errorf("expected '+' or '-'")
+		}
+
+		p.skipSpaces()
+
+		term, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		term.Sign = sign
+		expression.Terms = append(expression.Terms, term)
+
+		p.skipSpaces()
+
+		if len(expression.Terms) > maxTerms {
+			return nil, fmt.Errorf("%w: too many terms", ErrInvalidExpression)
+		}
+	}
+
+	return expression, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+// skipSpaces skips the spaces around operators. Spaces inside a term, as in
+// "1d20 3", are not skipped so that they do not join two numbers.
+func (p *parser) skipSpaces() {
+	for !p.done() {
+		r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+
+		p.pos += size
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d", ErrInvalidExpression, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *parser) accept(prefix string) bool {
+	if len(p.input)-p.pos >= len(prefix) && strings.EqualFold(p.input[p.pos:p.pos+len(prefix)], prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+
+	return false
+}
+
+// number reads an unsigned number, reporting whether there was one. A number
+// too large for an int is an error rather than no number.
+func (p *parser) number() (int, bool, error) {
+	start := p.pos
+	for !p.done() && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+
+	if start == p.pos {
+		return 0, false, nil
+	}
+
+	n, err := strconv.Atoi(p.input[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false, p.errorf("number is too large")
+	}
+
+	return n, true, nil
+}
+
+func (p *parser) term() (Term, error) {
+	if p.accept("@") {
+		start := p.pos
+		for !p.done() {
+			r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			if !unicode.IsLetter(r) && r != '_' {
+				break
+			}
+
+			p.pos += size
+		}
+
+		if start == p.pos {
+			return Term{}, p.errorf("expected a reference name after '@'")
+		}
+
+		return Term{Kind: TermReference, Reference: strings.ToUpper(p.input[start:p.pos])}, nil
+	}
+
+	count, hasCount, err := p.number()
+	if err != nil {
+		return Term{}, err
+	}
+
+	if !p.accept("d") {
+		if !hasCount {
+			return Term{}, p.errorf("expected a number, dice or reference")
+		}
+
+		return Term{Kind: TermConstant, Value: count}, nil
+	}
+
+	if !hasCount {
+		count = 1
+	}
+
+	sides, ok, err := p.number()
+	if err != nil {
+		return Term{}, err
+	}
+
+	if !ok {
+		return Term{}, p.errorf("expected the number of sides")
+	}
+
+	term := Term{Kind: TermDice, Count: count, Sides: sides}
+
+	switch {
+	case count < 1 || count > MaxDice:
+		return Term{}, p.errorf("dice count must be between 1 and %d", MaxDice)
+	case sides < 1 || sides > MaxSides:
+		return Term{}, p.errorf("dice sides must be between 1 and %d", MaxSides)
+	}
+
+	if p.accept("!") {
+		if sides < 2 {
+			return Term{}, p.errorf("single-sided dice cannot explode")
+		}
+
+		term.Explode = true
+	}
+
+	for _, mode := range []KeepMode{KeepHighest, KeepLowest} {
+		if p.accept(string(mode)) {
+			keepCount, ok, err := p.number()
+			if err != nil {
+				return Term{}, err
+			}
+
+			if !ok || keepCount < 1 || keepCount > count {
+				return Term{}, p.errorf("keep count must be between 1 and %d", count)
+			}
+
+			term.Keep = mode
+			term.KeepCount = keepCount
+
+			break
+		}
+	}
+
+	return term, nil
+}
@@ -0,0 +1,87 @@
+package dice
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2d6+3", "2d6+3"},
+		{"d20", "1d20"},
+		{" 1d20 + 3 ", "1d20+3"},
+		{"-2", "-2"},
+		{"1d20-@for", "1d20-@FOR"},
+		{"1d20 - @FOR\t", "1d20-@FOR"},
+		{"@Éloïse", "@ÉLOÏSE"},
+		{"@mana_max", "@MANA_MAX"},
+		{"2d20kh1", "2d20kh1"},
+		{"2D20KH1", "2d20kh1"},
+		{"4d6kl3", "4d6kl3"},
+		{"3d6!", "3d6!"},
+		{"3d6!kh2", "3d6!kh2"},
+		{"100d1000", "100d1000"},
+	}
+
+	for _, tt := range tests {
+		expression, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.input, err)
+			continue
+		}
+
+		if got := expression.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", "empty expression"},
+		{"blank", " \t", "empty expression"},
+		{"no dice", "0d6", "dice count must be between 1 and 100"},
+		{"too many dice", "101d6", "dice count must be between 1 and 100"},
+		{"no sides", "d0", "dice sides must be between 1 and 1000"},
+		{"too many sides", "1d1001", "dice sides must be between 1 and 1000"},
+		{"missing sides", "2d", "expected the number of sides"},
+		{"huge count", "99999999999999999999d6", "number is too large"},
+		{"huge sides", "1d99999999999999999999", "number is too large"},
+		{"huge constant", "1d20+99999999999999999999", "number is too large"},
+		{"trailing plus", "1d20+", "expected a number, dice or reference"},
+		{"trailing minus", "1d20 -", "expected a number, dice or reference"},
+		{"leading plus", "+1d20", "unexpected '+'"},
+		{"double operator", "1d20++2", "expected a number, dice or reference"},
+		{"missing operator", "1d20 3", "expected '+' or '-'"},
+		{"space in term", "1 d20", "expected '+' or '-'"},
+		{"unknown character", "1d20*2", "expected '+' or '-'"},
+		{"empty reference", "1d20+@", "expected a reference name after '@'"},
+		{"keep too many", "2d20kh3", "keep count must be between 1 and 2"},
+		{"keep none", "2d20kl0", "keep count must be between 1 and 2"},
+		{"keep without count", "2d20kh", "keep count must be between 1 and 2"},
+		{"single-sided explosion", "1d1!", "single-sided dice cannot explode"},
+		{"too many terms", "1" + strings.Repeat("+1", maxTerms), "too many terms"},
+		{"folded keep", "1d20Kh1", "expected '+' or '-'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			if !errors.Is(err, ErrInvalidExpression) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.input, err, ErrInvalidExpression)
+			}
+
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) error = %q, want it to mention %q", tt.input, err, tt.want)
+			}
+		})
+	}
+}
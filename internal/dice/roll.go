@@ -0,0 +1,193 @@
+package dice
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	mathrand "math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const maxExplosions = 20
+
+type Die struct {
+	Sides    int  `json:"sides"`
+	Value    int  `json:"value"`
+	Kept     bool `json:"kept"`
+	Exploded bool `json:"exploded,omitempty"`
+}
+
+type TermResult struct {
+	Term  Term  `json:"term"`
+	Dice  []Die `json:"dice,omitempty"`
+	Value int   `json:"value"`
+}
+
+type Result struct {
+	Expression string       `json:"expression"`
+	Terms      []TermResult `json:"terms"`
+	Total      int          `json:"total"`
+}
+
+// String gives a human readable breakdown, e.g. "2d20kh1 [17, (4)] + 3 = 20".
+func (r *Result) String() string {
+	var sb strings.Builder
+
+	for i, term := range r.Terms {
+		switch {
+		case term.Term.Sign < 0 && i == 0:
+			sb.WriteString("-")
+		case term.Term.Sign < 0:
+			sb.WriteString(" - ")
+		case i > 0:
+			sb.WriteString(" + ")
+		}
+
+		sb.WriteString(term.Term.String())
+
+		switch term.Term.Kind {
+		case TermDice:
+			values := make([]string, 0, len(term.Dice))
+			for _, die := range term.Dice {
+				value := fmt.Sprint(die.Value)
+				if die.Exploded {
+					value += "!"
+				}
+
+				if !die.Kept {
+					value = "(" + value + ")"
+				}
+
+				values = append(values, value)
+			}
+
+			fmt.Fprintf(&sb, " [%s]", strings.Join(values, ", "))
+		case TermReference:
+			fmt.Fprintf(&sb, " (%d)", term.Value)
+		case TermConstant:
+		}
+	}
+
+	fmt.Fprintf(&sb, " = %d", r.Total)
+
+	return sb.String()
+}
+
+// Roller rolls parsed expressions. It is safe for concurrent use.
+type Roller struct {
+	mu  sync.Mutex
+	rng *mathrand.Rand
+}
+
+// NewRoller uses the given source, which makes rolls reproducible in tests.
+func NewRoller(source mathrand.Source) *Roller {
+	return &Roller{rng: mathrand.New(source)}
+}
+
+// NewCryptoSeededRoller uses a source seeded from crypto/rand.
+func NewCryptoSeededRoller() (*Roller, error) {
+	var seed [8]byte
+
+	_, err := rand.Read(seed[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRoller(mathrand.NewSource(int64(binary.LittleEndian.Uint64(seed[:])))), nil
+}
+
+func (r *Roller) die(sides int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rng.Intn(sides) + 1
+}
+
+// Roll evaluates the expression. References such as "@FOR" are looked up in
+// refs and an unknown reference is an error.
+func (r *Roller) Roll(expression *Expression, refs map[string]int) (*Result, error) {
+	result := &Result{Expression: expression.String()}
+
+	for _, term := range expression.Terms {
+		termResult := TermResult{Term: term}
+
+		switch term.Kind {
+		case TermDice:
+			termResult.Dice, termResult.Value = r.rollDice(term)
+		case TermConstant:
+			termResult.Value = term.Value
+		case TermReference:
+			value, ok := refs[term.Reference]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown reference @%s", ErrInvalidExpression, term.Reference)
+			}
+
+			termResult.Value = value
+		}
+
+		result.Total += term.Sign * termResult.Value
+		result.Terms = append(result.Terms, termResult)
+	}
+
+	return result, nil
+}
+
+// RollString parses and rolls input in one go.
+func (r *Roller) RollString(input string, refs map[string]int) (*Result, error) {
+	expression, err := Parse(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Roll(expression, refs)
+}
+
+func (r *Roller) rollDice(term Term) ([]Die, int) {
+	dice := make([]Die, 0, term.Count)
+
+	for i := 0; i < term.Count; i++ {
+		die := Die{Sides: term.Sides, Value: r.die(term.Sides), Kept: true}
+
+		if term.Explode {
+			last := die.Value
+			for explosions := 0; last == term.Sides && explosions < maxExplosions; explosions++ {
+				last = r.die(term.Sides)
+				die.Value += last
+				die.Exploded = true
+			}
+		}
+
+		dice = append(dice, die)
+	}
+
+	if term.Keep != KeepAll {
+		order := make([]int, len(dice))
+		for i := range order {
+			order[i] = i
+		}
+
+		sort.SliceStable(order, func(a, b int) bool {
+			if term.Keep == KeepHighest {
+				return dice[order[a]].Value > dice[order[b]].Value
+			}
+
+			return dice[order[a]].Value < dice[order[b]].Value
+		})
+
+		for rank, i := range order {
+			dice[i].Kept = rank < term.KeepCount
+		}
+	}
+
+	total := 0
+
+	for _, die := range dice {
+		if die.Kept {
+			total += die.Value
+		}
+	}
+
+	return dice, total
+}
@@ -0,0 +1,100 @@
+package dice
+
+import (
+	"errors"
+	mathrand "math/rand"
+	"testing"
+)
+
+func TestRollSeeded(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		total int
+	}{
+		{"1d20", "1d20 [2] = 2", 2},
+		{"2d6+3", "2d6 [4, 6] + 3 = 13", 13},
+		{"2d20kh1", "2d20kh1 [20, (2)] = 20", 20},
+		{"4d6kl3", "4d6kl3 [1, 2, 3, (5)] = 6", 6},
+		{"3d6!", "3d6! [1, 3, 2] = 6", 6},
+		{"1d20+@FOR", "1d20 [3] + @FOR (2) = 5", 5},
+		{"-1d4-@FOR", "-1d4 [2] - @FOR (2) = -4", -4},
+	}
+
+	// One roller for the whole table: each roll continues the sequence.
+	roller := NewRoller(mathrand.NewSource(1))
+	refs := map[string]int{"FOR": 2}
+
+	for _, tt := range tests {
+		result, err := roller.RollString(tt.input, refs)
+		if err != nil {
+			t.Fatalf("RollString(%q) error = %v", tt.input, err)
+		}
+
+		if got := result.String(); got != tt.want {
+			t.Errorf("RollString(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+
+		if result.Total != tt.total {
+			t.Errorf("RollString(%q).Total = %d, want %d", tt.input, result.Total, tt.total)
+		}
+	}
+}
+
+func TestRollReproducible(t *testing.T) {
+	expression, err := Parse("10d20!kh5+2d6")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := NewRoller(mathrand.NewSource(42)).Roll(expression, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewRoller(mathrand.NewSource(42)).Roll(expression, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.String() != second.String() {
+		t.Errorf("rolls with the same seed differ: %q and %q", first, second)
+	}
+}
+
+func TestRollDiceInRange(t *testing.T) {
+	roller := NewRoller(mathrand.NewSource(7))
+
+	for i := 0; i < 200; i++ {
+		result, err := roller.RollString("4d6kh3", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		kept, total := 0, 0
+
+		for _, die := range result.Terms[0].Dice {
+			if die.Value < 1 || die.Value > 6 {
+				t.Fatalf("die value %d out of range in %q", die.Value, result)
+			}
+
+			if die.Kept {
+				kept++
+				total += die.Value
+			}
+		}
+
+		if kept != 3 || total != result.Total {
+			t.Fatalf("%q keeps %d dice totalling %d", result, kept, total)
+		}
+	}
+}
+
+func TestRollUnknownReference(t *testing.T) {
+	roller := NewRoller(mathrand.NewSource(1))
+
+	_, err := roller.RollString("1d20+@XYZ", map[string]int{"FOR": 2})
+	if !errors.Is(err, ErrInvalidExpression) {
+		t.Errorf("RollString() error = %v, want %v", err, ErrInvalidExpression)
+	}
+}
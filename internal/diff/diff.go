@@ -0,0 +1,174 @@
+package diff
+
+import (
+	"strings"
+)
+
+// maxTableSize bounds the LCS table. Beyond that, the differing middle part
+// of two texts is reported as a single replacement.
+const maxTableSize = 4_000_000
+
+type OpKind string
+
+const (
+	OpEqual  OpKind = "equal"
+	OpInsert OpKind = "insert"
+	OpDelete OpKind = "delete"
+	// OpSkip stands for unchanged lines left out by WithContext.
+	OpSkip OpKind = "skip"
+)
+
+type Op struct {
+	Kind OpKind
+	Line string
+}
+
+// SplitLines splits s into lines, keeping line endings so that joining the
+// lines gives back s exactly.
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// Lines compares two texts line by line.
+func Lines(a, b string) []Op {
+	return Diff(SplitLines(a), SplitLines(b))
+}
+
+// Diff returns the edit script turning a into b.
+func Diff(a, b []string) []Op {
+	matches := match(a, b)
+	ops := make([]Op, 0, len(a)+len(b))
+
+	i, j := 0, 0
+
+	for _, m := range matches {
+		for ; i < m[0]; i++ {
+			ops = append(ops, Op{Kind: OpDelete, Line: a[i]})
+		}
+
+		for ; j < m[1]; j++ {
+			ops = append(ops, Op{Kind: OpInsert, Line: b[j]})
+		}
+
+		ops = append(ops, Op{Kind: OpEqual, Line: a[i]})
+		i++
+		j++
+	}
+
+	for ; i < len(a); i++ {
+		ops = append(ops, Op{Kind: OpDelete, Line: a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		ops = append(ops, Op{Kind: OpInsert, Line: b[j]})
+	}
+
+	return ops
+}
+
+// WithContext keeps the changes and up to n unchanged lines around each of
+// them. Every run of unchanged lines left out is replaced by a single OpSkip.
+func WithContext(ops []Op, n int) []Op {
+	keep := make([]bool, len(ops))
+
+	for i, op := range ops {
+		if op.Kind == OpEqual {
+			continue
+		}
+
+		for j := max(0, i-n); j <= min(len(ops)-1, i+n); j++ {
+			keep[j] = true
+		}
+	}
+
+	var trimmed []Op
+
+	for i, op := range ops {
+		switch {
+		case keep[i]:
+			trimmed = append(trimmed, op)
+		case len(trimmed) == 0 || trimmed[len(trimmed)-1].Kind != OpSkip:
+			trimmed = append(trimmed, Op{Kind: OpSkip})
+		}
+	}
+
+	return trimmed
+}
+
+// match returns the index pairs of a longest common subsequence of a and b,
+// in increasing order.
+func match(a, b []string) [][2]int {
+	var matches [][2]int
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		matches = append(matches, [2]int{prefix, prefix})
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+
+	if len(midA)*len(midB) <= maxTableSize {
+		for _, m := range lcs(midA, midB) {
+			matches = append(matches, [2]int{m[0] + prefix, m[1] + prefix})
+		}
+	}
+
+	for k := suffix; k > 0; k-- {
+		matches = append(matches, [2]int{len(a) - k, len(b) - k})
+	}
+
+	return matches
+}
+
+func lcs(a, b []string) [][2]int {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+
+	width := len(b) + 1
+	table := make([]int32, (len(a)+1)*width)
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i*width+j] = table[(i+1)*width+j+1] + 1
+			} else {
+				table[i*width+j] = max(table[(i+1)*width+j], table[i*width+j+1])
+			}
+		}
+	}
+
+	var matches [][2]int
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			matches = append(matches, [2]int{i, j})
+			i++
+			j++
+		case table[(i+1)*width+j] >= table[i*width+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return matches
+}
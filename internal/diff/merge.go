@@ -0,0 +1,168 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+type Resolution string
+
+const (
+	ResolutionOurs   Resolution = "ours"
+	ResolutionTheirs Resolution = "theirs"
+	ResolutionBoth   Resolution = "both"
+)
+
+// Chunk is a run of merged lines, or a conflict when both sides changed the
+// same region of the base differently.
+type Chunk struct {
+	Conflict bool
+	Lines    []string
+	Base     []string
+	Ours     []string
+	Theirs   []string
+}
+
+type Merge struct {
+	Chunks []Chunk
+}
+
+func (m *Merge) Conflicts() int {
+	n := 0
+
+	for _, chunk := range m.Chunks {
+		if chunk.Conflict {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (m *Merge) HasConflicts() bool {
+	return m.Conflicts() > 0
+}
+
+// Resolve joins the merge, picking a side for each conflict in order. Missing
+// resolutions keep our side.
+func (m *Merge) Resolve(resolutions []Resolution) string {
+	var sb strings.Builder
+
+	conflict := 0
+
+	for _, chunk := range m.Chunks {
+		if !chunk.Conflict {
+			sb.WriteString(strings.Join(chunk.Lines, ""))
+			continue
+		}
+
+		resolution := ResolutionOurs
+		if conflict < len(resolutions) {
+			resolution = resolutions[conflict]
+		}
+
+		conflict++
+
+		switch resolution {
+		case ResolutionTheirs:
+			sb.WriteString(strings.Join(chunk.Theirs, ""))
+		case ResolutionBoth:
+			sb.WriteString(withTrailingNewline(strings.Join(chunk.Ours, "")))
+			sb.WriteString(strings.Join(chunk.Theirs, ""))
+		case ResolutionOurs:
+			sb.WriteString(strings.Join(chunk.Ours, ""))
+		}
+	}
+
+	return sb.String()
+}
+
+// Text is the merged text, only meaningful when there are no conflicts.
+func (m *Merge) Text() string {
+	return m.Resolve(nil)
+}
+
+func (m *Merge) appendLines(lines ...string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	last := len(m.Chunks) - 1
+	if last >= 0 && !m.Chunks[last].Conflict {
+		m.Chunks[last].Lines = append(m.Chunks[last].Lines, lines...)
+		return
+	}
+
+	m.Chunks = append(m.Chunks, Chunk{Lines: slices.Clone(lines)})
+}
+
+func (m *Merge) appendUnstable(base, ours, theirs []string) {
+	switch {
+	case slices.Equal(ours, base):
+		m.appendLines(theirs...)
+	case slices.Equal(theirs, base), slices.Equal(ours, theirs):
+		m.appendLines(ours...)
+	default:
+		m.Chunks = append(m.Chunks, Chunk{Conflict: true, Base: base, Ours: ours, Theirs: theirs})
+	}
+}
+
+// Merge3 performs a line based three-way merge of ours and theirs, which
+// were both derived from base. Line endings are normalized first, since
+// browsers do not submit them consistently.
+func Merge3(base, ours, theirs string) *Merge {
+	baseLines := SplitLines(NormalizeNewlines(base))
+	ourLines := SplitLines(NormalizeNewlines(ours))
+	theirLines := SplitLines(NormalizeNewlines(theirs))
+
+	inOurs := matchIndex(len(baseLines), match(baseLines, ourLines))
+	inTheirs := matchIndex(len(baseLines), match(baseLines, theirLines))
+
+	merge := &Merge{}
+
+	o, a, b := 0, 0, 0
+
+	for i := range baseLines {
+		if inOurs[i] < 0 || inTheirs[i] < 0 {
+			continue
+		}
+
+		merge.appendUnstable(baseLines[o:i], ourLines[a:inOurs[i]], theirLines[b:inTheirs[i]])
+		merge.appendLines(baseLines[i])
+
+		o, a, b = i+1, inOurs[i]+1, inTheirs[i]+1
+	}
+
+	merge.appendUnstable(baseLines[o:], ourLines[a:], theirLines[b:])
+
+	return merge
+}
+
+func matchIndex(n int, matches [][2]int) []int {
+	index := make([]int, n)
+	for i := range index {
+		index[i] = -1
+	}
+
+	for _, m := range matches {
+		index[m[0]] = m[1]
+	}
+
+	return index
+}
+
+func withTrailingNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+
+	return s + "\n"
+}
+
+// NormalizeNewlines turns "\r\n" and lone "\r" line endings into "\n".
+// Django stores notes as a textarea submits them, with "\r\n", while
+// browsers drop the "\r" from attribute values.
+func NormalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
@@ -0,0 +1,140 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts int
+	}{
+		{
+			name:   "edits to different lines",
+			base:   "a\nb\nc\nd\n",
+			ours:   "A\nb\nc\nd\n",
+			theirs: "a\nb\nc\nD\n",
+			want:   "A\nb\nc\nD\n",
+		},
+		{
+			name:   "insertion and deletion",
+			base:   "a\nb\nc\n",
+			ours:   "a\nnew\nb\nc\n",
+			theirs: "a\nb\n",
+			want:   "a\nnew\nb\n",
+		},
+		{
+			name:   "same edit on both sides",
+			base:   "a\nb\n",
+			ours:   "a\nB\n",
+			theirs: "a\nB\n",
+			want:   "a\nB\n",
+		},
+		{
+			name:   "only theirs changed",
+			base:   "a\nb\n",
+			ours:   "a\nb\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "windows line endings",
+			base:   "a\r\nb\r\nc\r\n",
+			ours:   "A\nb\nc\n",
+			theirs: "a\r\nb\r\nC\r\n",
+			want:   "A\nb\nC\n",
+		},
+		{
+			name:      "same line changed differently",
+			base:      "a\nb\nc\n",
+			ours:      "a\nours\nc\n",
+			theirs:    "a\ntheirs\nc\n",
+			want:      "a\nours\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "two conflicts",
+			base:      "a\nb\nc\nd\ne\n",
+			ours:      "A1\nb\nc\nd\nE1\n",
+			theirs:    "A2\nb\nc\nd\nE2\n",
+			want:      "A1\nb\nc\nd\nE1\n",
+			conflicts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merge := Merge3(tt.base, tt.ours, tt.theirs)
+
+			if got := merge.Conflicts(); got != tt.conflicts {
+				t.Errorf("Conflicts() = %d, want %d", got, tt.conflicts)
+			}
+
+			if got := merge.Text(); got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge3ConflictChunk(t *testing.T) {
+	merge := Merge3("a\nb\nc\n", "a\nours\nc\n", "a\ntheirs\nc\n")
+
+	want := []Chunk{
+		{Lines: []string{"a\n"}},
+		{Conflict: true, Base: []string{"b\n"}, Ours: []string{"ours\n"}, Theirs: []string{"theirs\n"}},
+		{Lines: []string{"c\n"}},
+	}
+
+	if !slices.EqualFunc(merge.Chunks, want, equalChunks) {
+		t.Errorf("Chunks = %+v, want %+v", merge.Chunks, want)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	merge := Merge3(
+		"title\nb\nc\nd\nend",
+		"title\nours 1\nc\nd\nours 2",
+		"title\ntheirs 1\nc\nd\ntheirs 2",
+	)
+
+	tests := []struct {
+		name        string
+		resolutions []Resolution
+		want        string
+	}{
+		{"ours", []Resolution{ResolutionOurs, ResolutionOurs}, "title\nours 1\nc\nd\nours 2"},
+		{"theirs", []Resolution{ResolutionTheirs, ResolutionTheirs}, "title\ntheirs 1\nc\nd\ntheirs 2"},
+		{"both", []Resolution{ResolutionBoth, ResolutionBoth}, "title\nours 1\ntheirs 1\nc\nd\nours 2\ntheirs 2"},
+		{"mixed", []Resolution{ResolutionTheirs, ResolutionOurs}, "title\ntheirs 1\nc\nd\nours 2"},
+		{"missing resolutions keep ours", []Resolution{ResolutionTheirs}, "title\ntheirs 1\nc\nd\nours 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := merge.Resolve(tt.resolutions); got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeNewlines(t *testing.T) {
+	got := NormalizeNewlines("a\r\nb\rc\n\r\n")
+	if want := "a\nb\nc\n\n"; got != want {
+		t.Errorf("NormalizeNewlines() = %q, want %q", got, want)
+	}
+}
+
+func equalChunks(a, b Chunk) bool {
+	return a.Conflict == b.Conflict &&
+		slices.Equal(a.Lines, b.Lines) &&
+		slices.Equal(a.Base, b.Base) &&
+		slices.Equal(a.Ours, b.Ours) &&
+		slices.Equal(a.Theirs, b.Theirs)
+}
@@ -0,0 +1,42 @@
+package django
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+)
+
+const sessionAuthHashSalt = "django.contrib.auth.models.AbstractBaseUser.get_session_auth_hash"
+
+// SessionAuthHash mirrors AbstractBaseUser.get_session_auth_hash.
+func SessionAuthHash(hashedPassword, secret string) (string, error) {
+	mac, err := SaltedHMAC(sessionAuthHashSalt, hashedPassword, secret, AlgorithmSHA256)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(mac), nil
+}
+
+// VerifySessionAuthHash reports whether sessionHash was derived from
+// hashedPassword with one of the given secrets. Hashes computed by Django
+// versions using sha1 are still accepted.
+func VerifySessionAuthHash(sessionHash, hashedPassword string, secrets []string) (bool, error) {
+	if sessionHash == "" {
+		return false, nil
+	}
+
+	for _, secret := range secrets {
+		for _, algorithm := range []string{AlgorithmSHA256, AlgorithmSHA1} {
+			mac, err := SaltedHMAC(sessionAuthHashSalt, hashedPassword, secret, algorithm)
+			if err != nil {
+				return false, err
+			}
+
+			if hmac.Equal([]byte(sessionHash), []byte(hex.EncodeToString(mac))) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
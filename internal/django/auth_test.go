@@ -0,0 +1,57 @@
+package django
+
+import (
+	"testing"
+)
+
+// djangoPassword is the pbkdf2_sha256 hash of "lètmein" from Django's
+// tests/auth_tests/test_hashers.py.
+const djangoPassword = "pbkdf2_sha256$720000$seasalt$eDupbcisD1UuIiou3hMuMu8oe/XwnpDw45r6AA5iv0E="
+
+func TestSessionAuthHash(t *testing.T) {
+	got, err := SessionAuthHash(djangoPassword, djangoTestsSecretKey)
+	if err != nil {
+		t.Fatalf("SessionAuthHash() error = %v", err)
+	}
+
+	if want := "9a3bcff6efe06099a71921af7ff1df4742c7aefa2780262b8d71cf6e9e40877d"; got != want {
+		t.Errorf("SessionAuthHash() = %s, want %s", got, want)
+	}
+}
+
+func TestVerifySessionAuthHash(t *testing.T) {
+	const (
+		sha256Hash = "9a3bcff6efe06099a71921af7ff1df4742c7aefa2780262b8d71cf6e9e40877d"
+		// As computed by Django before 3.1.
+		sha1Hash = "dafa63e8079e637028d04dedf812b1f7f0fd8873"
+	)
+
+	tests := []struct {
+		name           string
+		sessionHash    string
+		hashedPassword string
+		secrets        []string
+		want           bool
+	}{
+		{"sha256", sha256Hash, djangoPassword, []string{djangoTestsSecretKey}, true},
+		{"legacy sha1", sha1Hash, djangoPassword, []string{djangoTestsSecretKey}, true},
+		{"fallback secret", sha256Hash, djangoPassword, []string{"new-secret", djangoTestsSecretKey}, true},
+		{"unknown secret", sha256Hash, djangoPassword, []string{"new-secret"}, false},
+		{"password changed", sha256Hash, "pbkdf2_sha256$720000$seasalt$other", []string{djangoTestsSecretKey}, false},
+		{"tampered hash", "9a3bcff6efe06099a71921af7ff1df4742c7aefa2780262b8d71cf6e9e40877e", djangoPassword, []string{djangoTestsSecretKey}, false},
+		{"empty hash", "", djangoPassword, []string{djangoTestsSecretKey}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifySessionAuthHash(tt.sessionHash, tt.hashedPassword, tt.secrets)
+			if err != nil {
+				t.Fatalf("VerifySessionAuthHash() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("VerifySessionAuthHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package django
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // Django legacy signatures use sha1
+	"crypto/sha256"
+	"fmt"
+	"hash"
+)
+
+const (
+	AlgorithmSHA1   = "sha1"
+	AlgorithmSHA256 = "sha256"
+)
+
+func newHash(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case AlgorithmSHA1:
+		return sha1.New, nil
+	case AlgorithmSHA256:
+		return sha256.New, nil
+	}
+
+	return nil, fmt.Errorf("unsupported hash algorithm %q", algorithm)
+}
+
+// SaltedHMAC mirrors django.utils.crypto.salted_hmac.
+func SaltedHMAC(keySalt, value, secret, algorithm string) ([]byte, error) {
+	hasher, err := newHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	keyHash := hasher()
+	keyHash.Write([]byte(keySalt + secret))
+	key := keyHash.Sum(nil)
+
+	mac := hmac.New(hasher, key)
+	mac.Write([]byte(value))
+
+	return mac.Sum(nil), nil
+}
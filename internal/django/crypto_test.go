@@ -0,0 +1,45 @@
+package django
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// djangoTestsSecretKey is the SECRET_KEY of Django's own test settings, under
+// which the known answers below were computed.
+const djangoTestsSecretKey = "django_tests_secret_key"
+
+func TestSaltedHMAC(t *testing.T) {
+	// From Django's tests/utils_tests/test_crypto.py.
+	tests := []struct {
+		secret    string
+		algorithm string
+		want      string
+	}{
+		{djangoTestsSecretKey, AlgorithmSHA1, "b51a2e619c43b1ca4f91d15c57455521d71d61eb"},
+		{"abcdefg", AlgorithmSHA1, "8bbee04ccddfa24772d1423a0ba43bd0c0e24b76"},
+		{strings.Repeat("x", 64), AlgorithmSHA1, "bd3749347b412b1b0a9ea65220e55767ac8e96b0"},
+		{djangoTestsSecretKey, AlgorithmSHA256, "ee0bf789e4e009371a5372c90f73fcf17695a8439c9108b0480f14e347b3f9ec"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm+" "+tt.secret, func(t *testing.T) {
+			mac, err := SaltedHMAC("salt", "value", tt.secret, tt.algorithm)
+			if err != nil {
+				t.Fatalf("SaltedHMAC() error = %v", err)
+			}
+
+			if got := hex.EncodeToString(mac); got != tt.want {
+				t.Errorf("SaltedHMAC() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaltedHMACUnsupportedAlgorithm(t *testing.T) {
+	_, err := SaltedHMAC("salt", "value", djangoTestsSecretKey, "md5")
+	if err == nil {
+		t.Error("SaltedHMAC() with md5 error = nil, want an error")
+	}
+}
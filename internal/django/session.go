@@ -0,0 +1,36 @@
+package django
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	ModelBackend = "django.contrib.auth.backends.ModelBackend"
+
+	SessionCookieName = "sessionid"
+	SessionCookieAge  = 14 * 24 * time.Hour
+
+	sessionKeyLength = 32
+	sessionKeyChars  = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// NewSessionKey mirrors SessionBase._get_new_session_key.
+func NewSessionKey() (string, error) {
+	var sb strings.Builder
+
+	limit := big.NewInt(int64(len(sessionKeyChars)))
+
+	for i := 0; i < sessionKeyLength; i++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteByte(sessionKeyChars[n.Int64()])
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,228 @@
+package django
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signerSalt is the default salt of django.core.signing.Signer.
+const signerSalt = "django.core.signing.Signer"
+
+func TestSign(t *testing.T) {
+	// From Django's tests/signing/tests.py, test_works_with_non_ascii_keys.
+	signer := NewSigner("\xe7", nil, signerSalt)
+
+	got, err := signer.Sign("foo")
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if want := "foo:EE4qGC5MEKyQG5msxYA0sBohAxLC0BJf8uRhemh0BGU"; got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+
+	value, err := signer.Unsign(got)
+	if err != nil || value != "foo" {
+		t.Errorf("Unsign(%q) = %q, %v, want %q", got, value, err, "foo")
+	}
+}
+
+func TestUnsign(t *testing.T) {
+	// The sha1 signature comes from test_legacy_signature in Django's
+	// tests/signing/tests.py.
+	tests := []struct {
+		name    string
+		signer  *Signer
+		signed  string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "sha256",
+			signer: NewSigner("\xe7", nil, signerSalt),
+			signed: "foo:EE4qGC5MEKyQG5msxYA0sBohAxLC0BJf8uRhemh0BGU",
+			want:   "foo",
+		},
+		{
+			name:   "legacy sha1",
+			signer: NewSigner(djangoTestsSecretKey, nil, signerSalt),
+			signed: "foo:l-EMM5FtewpcHMbKFeQodt3X9z8",
+			want:   "foo",
+		},
+		{
+			name:   "fallback key",
+			signer: NewSigner("new-secret", []string{"other-secret", djangoTestsSecretKey}, signerSalt),
+			signed: "foo:l-EMM5FtewpcHMbKFeQodt3X9z8",
+			want:   "foo",
+		},
+		{
+			name:    "unknown key",
+			signer:  NewSigner("new-secret", []string{"other-secret"}, signerSalt),
+			signed:  "foo:l-EMM5FtewpcHMbKFeQodt3X9z8",
+			wantErr: ErrBadSignature,
+		},
+		{
+			name:    "other salt",
+			signer:  NewSigner(djangoTestsSecretKey, nil, SessionSalt),
+			signed:  "foo:l-EMM5FtewpcHMbKFeQodt3X9z8",
+			wantErr: ErrBadSignature,
+		},
+		{
+			name:    "tampered value",
+			signer:  NewSigner(djangoTestsSecretKey, nil, signerSalt),
+			signed:  "fop:l-EMM5FtewpcHMbKFeQodt3X9z8",
+			wantErr: ErrBadSignature,
+		},
+		{
+			name:    "tampered signature",
+			signer:  NewSigner(djangoTestsSecretKey, nil, signerSalt),
+			signed:  "foo:l-EMM5FtewpcHMbKFeQodt3X9z9",
+			wantErr: ErrBadSignature,
+		},
+		{
+			name:    "no signature",
+			signer:  NewSigner(djangoTestsSecretKey, nil, signerSalt),
+			signed:  "foo",
+			wantErr: ErrBadSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.signer.Unsign(tt.signed)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unsign() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Unsign() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsignObject(t *testing.T) {
+	// The first value comes from test_dumps_loads_legacy_signature in
+	// Django's tests/signing/tests.py. The session payloads were signed on
+	// 2023-11-14 the way django.core.signing.dumps does, the first one with
+	// compress=True.
+	const (
+		legacy     = "ImEgc3RyaW5nIFx1MjAyMCI:1k1beT:ZfNhN1kdws7KosUleOvuYroPHEc"
+		compressed = ".eJyrVopPLC3JiC8tTi2Kz0xRslIyVNJBFktKTM5OzQNJpGQl5qXn6yXn55UUZSbpgZToQWWL9XzzU1JznKBqUQzISCzOAOo2oBAo1QIAIvYyTQ:1r31eq:7u6iuun-SE8thNK_iYqH-_k7qUf7sp9nz20ZzyR9CQ4"
+		fallback   = "eyJfYXV0aF91c2VyX2lkIjoiMSJ9:1r31eq:wf8F9o5-QlkCVyNOUOoZP7ntwbK726mQfD2htm9TGY0"
+	)
+
+	session := NewSigner(djangoTestsSecretKey, []string{"old-secret"}, SessionSalt)
+
+	t.Run("legacy sha1", func(t *testing.T) {
+		var got string
+
+		err := NewSigner(djangoTestsSecretKey, nil, "django.core.signing").UnsignObject(legacy, 0, &got)
+		if err != nil {
+			t.Fatalf("UnsignObject() error = %v", err)
+		}
+
+		if want := "a string †"; got != want {
+			t.Errorf("UnsignObject() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		var got map[string]string
+
+		err := session.UnsignObject(compressed, 0, &got)
+		if err != nil {
+			t.Fatalf("UnsignObject() error = %v", err)
+		}
+
+		if got["_auth_user_id"] != "1" || got["_auth_user_backend"] != ModelBackend {
+			t.Errorf("UnsignObject() = %v", got)
+		}
+	})
+
+	t.Run("fallback key", func(t *testing.T) {
+		var got map[string]string
+
+		err := session.UnsignObject(fallback, 0, &got)
+		if err != nil {
+			t.Fatalf("UnsignObject() error = %v", err)
+		}
+
+		if got["_auth_user_id"] != "1" {
+			t.Errorf("UnsignObject() = %v", got)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		var got map[string]string
+
+		err := session.UnsignObject(compressed, time.Hour, &got)
+		if !errors.Is(err, ErrSignatureExpired) {
+			t.Errorf("UnsignObject() error = %v, want %v", err, ErrSignatureExpired)
+		}
+	})
+
+	t.Run("tampered payload", func(t *testing.T) {
+		var got map[string]string
+
+		// The same payload, uncompressed and giving user 2.
+		tampered := "eyJfYXV0aF91c2VyX2lkIjoiMiJ9:1r31eq:wf8F9o5-QlkCVyNOUOoZP7ntwbK726mQfD2htm9TGY0"
+
+		err := session.UnsignObject(tampered, 0, &got)
+		if !errors.Is(err, ErrBadSignature) {
+			t.Errorf("UnsignObject() error = %v, want %v", err, ErrBadSignature)
+		}
+	})
+
+	t.Run("tampered timestamp", func(t *testing.T) {
+		var got map[string]string
+
+		tampered := "eyJfYXV0aF91c2VyX2lkIjoiMSJ9:1r31er:wf8F9o5-QlkCVyNOUOoZP7ntwbK726mQfD2htm9TGY0"
+
+		err := session.UnsignObject(tampered, 0, &got)
+		if !errors.Is(err, ErrBadSignature) {
+			t.Errorf("UnsignObject() error = %v, want %v", err, ErrBadSignature)
+		}
+	})
+}
+
+func TestSignObject(t *testing.T) {
+	signer := NewSigner(djangoTestsSecretKey, nil, SessionSalt)
+
+	tests := []struct {
+		name           string
+		value          map[string]string
+		wantCompressed bool
+	}{
+		{"short payload", map[string]string{"_auth_user_id": "1"}, false},
+		{"long repetitive payload", map[string]string{"_auth_user_hash": strings.Repeat("0", 1000)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := signer.SignObject(tt.value)
+			if err != nil {
+				t.Fatalf("SignObject() error = %v", err)
+			}
+
+			if compressed := signed[0] == '.'; compressed != tt.wantCompressed {
+				t.Errorf("SignObject() = %q, compressed %v, want %v", signed, compressed, tt.wantCompressed)
+			}
+
+			var got map[string]string
+
+			err = signer.UnsignObject(signed, time.Minute, &got)
+			if err != nil {
+				t.Fatalf("UnsignObject() error = %v", err)
+			}
+
+			for key, value := range tt.value {
+				if got[key] != value {
+					t.Errorf("UnsignObject()[%q] = %q, want %q", key, got[key], value)
+				}
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/Crocmagnon/charasheet-go/internal/rules"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 )
@@ -52,6 +53,11 @@ var TemplateFuncs = template.FuncMap{
 	// URL functions
 	"urlSetParam": urlSetParam,
 	"urlDelParam": urlDelParam,
+
+	// Game functions
+	"stats":    rules.Compute,
+	"modifier": rules.Modifier,
+	"signed":   signed,
 }
 
 func formatTime(format string, t time.Time) string {
@@ -177,6 +183,15 @@ func yesno(b bool) string {
 	return "No"
 }
 
+func signed(i any) (string, error) {
+	n, err := toInt64(i)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%+d", n), nil
+}
+
 func urlSetParam(u *url.URL, key string, value any) *url.URL {
 	nu := *u
 	values := nu.Query()
@@ -0,0 +1,268 @@
+package markdown
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+	"github.com/Crocmagnon/charasheet-go/internal/rules"
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/parser"
+)
+
+// Generated HTML is swapped in after sanitizing, where the renderer left
+// these private use characters around the index of the replacement.
+const (
+	placeholderStart = '\uE000'
+	placeholderEnd   = '\uE001'
+)
+
+const maxInlineLength = 100
+
+// Character is a character that notes can link to.
+type Character struct {
+	ID   int
+	Name string
+}
+
+// Context enables the note extensions: "[[1d20+5]]" becomes a button rolling
+// the dice by posting to RollURL, "[[char:12]]" and "@Name" link to the
+// matching Characters. Without a roll URL or a matching character, the
+// source text is shown as is.
+type Context struct {
+	RollURL    string
+	Characters []Character
+}
+
+// Roll is an inline dice expression such as "[[1d20+5]]".
+type Roll struct {
+	ast.Leaf
+	Expression string
+}
+
+// CharacterReference is "[[char:12]]" or "@Name".
+type CharacterReference struct {
+	ast.Leaf
+	ID   int
+	Name string
+}
+
+func registerExtensions(p *parser.Parser) {
+	var link func(p *parser.Parser, data []byte, offset int) (int, ast.Node)
+
+	link = p.RegisterInline('[', func(p *parser.Parser, data []byte, offset int) (int, ast.Node) {
+		consumed, node := doubleBracket(data[offset:])
+		if consumed > 0 {
+			return consumed, node
+		}
+
+		return link(p, data, offset)
+	})
+
+	var escape func(p *parser.Parser, data []byte, offset int) (int, ast.Node)
+
+	// Markdown does not escape "@", so "\@Name" is handled here to keep it
+	// from becoming a mention.
+	escape = p.RegisterInline('\\', func(p *parser.Parser, data []byte, offset int) (int, ast.Node) {
+		if offset+1 < len(data) && data[offset+1] == '@' {
+			return 2, &ast.Text{Leaf: ast.Leaf{Literal: data[offset+1 : offset+2]}}
+		}
+
+		return escape(p, data, offset)
+	})
+
+	p.RegisterInline('@', func(p *parser.Parser, data []byte, offset int) (int, ast.Node) {
+		if offset > 0 && isWordByte(data[offset-1]) {
+			return 0, nil
+		}
+
+		return mention(data[offset:])
+	})
+}
+
+// doubleBracket parses "[[1d20+5]]" and "[[char:12]]" at the start of data.
+func doubleBracket(data []byte) (int, ast.Node) {
+	if len(data) < 4 || data[1] != '[' {
+		return 0, nil
+	}
+
+	end := strings.Index(string(data[2:min(len(data), maxInlineLength)]), "]]")
+	if end < 0 {
+		return 0, nil
+	}
+
+	source := data[:end+4]
+	content := string(data[2 : end+2])
+
+	if strings.ContainsAny(content, "[\n") {
+		return 0, nil
+	}
+
+	content = strings.TrimSpace(content)
+
+	if ref, ok := strings.CutPrefix(strings.ToLower(content), "char:"); ok {
+		id, err := strconv.Atoi(strings.TrimSpace(ref))
+		if err != nil || id <= 0 {
+			return 0, nil
+		}
+
+		return len(source), &CharacterReference{Leaf: ast.Leaf{Literal: source}, ID: id}
+	}
+
+	expression, err := rules.ParseDice(content)
+	if err != nil || !slices.ContainsFunc(expression.Terms, func(t dice.Term) bool { return t.Kind == dice.TermDice }) {
+		return 0, nil
+	}
+
+	return len(source), &Roll{Leaf: ast.Leaf{Literal: source}, Expression: expression.String()}
+}
+
+// mention parses "@Name" at the start of data. Names are single words; other
+// characters are referenced by id.
+func mention(data []byte) (int, ast.Node) {
+	end := 1
+	for end < len(data) && end <= maxInlineLength && (isWordByte(data[end]) || data[end] == '-') {
+		end++
+	}
+
+	for end > 1 && data[end-1] == '-' {
+		end--
+	}
+
+	if end == 1 {
+		return 0, nil
+	}
+
+	return end, &CharacterReference{Leaf: ast.Leaf{Literal: data[:end]}, Name: string(data[1:end])}
+}
+
+// isWordByte treats every non-ASCII byte as part of a word, so that accented
+// names are read whole.
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_' || b >= 0x80
+}
+
+type extensionRenderer struct {
+	context      *Context
+	replacements []string
+}
+
+func (e *extensionRenderer) renderNode(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+	var replacement string
+
+	switch node := node.(type) {
+	case *Roll:
+		replacement = e.roll(node)
+	case *CharacterReference:
+		replacement = e.characterLink(node)
+	default:
+		return ast.GoToNext, false
+	}
+
+	if replacement == "" || insideLinkOrImage(node) {
+		io.WriteString(w, html.EscapeString(string(node.AsLeaf().Literal)))
+		return ast.GoToNext, true
+	}
+
+	fmt.Fprintf(w, "%c%d%c", placeholderStart, len(e.replacements), placeholderEnd)
+	e.replacements = append(e.replacements, replacement)
+
+	return ast.GoToNext, true
+}
+
+func (e *extensionRenderer) roll(node *Roll) string {
+	if e.context == nil || e.context.RollURL == "" {
+		return ""
+	}
+
+	vals, err := json.Marshal(map[string]string{"Expression": node.Expression})
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		`<button type="button" class="roll-inline" hx-post="%s" hx-vals="%s" hx-target="#dice-roller" hx-swap="outerHTML">%s</button>`,
+		html.EscapeString(e.context.RollURL), html.EscapeString(string(vals)), html.EscapeString(node.Expression),
+	)
+}
+
+func (e *extensionRenderer) characterLink(node *CharacterReference) string {
+	if e.context == nil {
+		return ""
+	}
+
+	matches := 0
+
+	var target Character
+
+	for _, character := range e.context.Characters {
+		if node.ID != 0 && character.ID == node.ID || node.ID == 0 && strings.EqualFold(character.Name, node.Name) {
+			target = character
+			matches++
+		}
+	}
+
+	// A name shared by several characters is ambiguous and left as text.
+	if matches != 1 {
+		return ""
+	}
+
+	return fmt.Sprintf(`<a href="/character/%d" class="character-link">%s</a>`, target.ID, html.EscapeString(target.Name))
+}
+
+func (e *extensionRenderer) replace(s string) string {
+	var sb strings.Builder
+
+	for {
+		start := strings.IndexRune(s, placeholderStart)
+		if start < 0 {
+			break
+		}
+
+		length := strings.IndexRune(s[start:], placeholderEnd)
+		if length < 0 {
+			break
+		}
+
+		sb.WriteString(s[:start])
+
+		i, err := strconv.Atoi(s[start+len(string(placeholderStart)) : start+length])
+		if err == nil && i >= 0 && i < len(e.replacements) {
+			sb.WriteString(e.replacements[i])
+		}
+
+		s = s[start+length+len(string(placeholderEnd)):]
+	}
+
+	sb.WriteString(s)
+
+	return sb.String()
+}
+
+// insideLinkOrImage reports whether node is part of a link text, where
+// nested links are invalid, or of an image alt text, where no markup belongs.
+func insideLinkOrImage(node ast.Node) bool {
+	for parent := node.GetParent(); parent != nil; parent = parent.GetParent() {
+		switch parent.(type) {
+		case *ast.Link, *ast.Image:
+			return true
+		}
+	}
+
+	return false
+}
+
+func stripPlaceholders(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == placeholderStart || r == placeholderEnd {
+			return -1
+		}
+
+		return r
+	}, s)
+}
@@ -0,0 +1,92 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderExtensions(t *testing.T) {
+	roll := func(expression string) string {
+		return `<button type="button" class="roll-inline" hx-post="/character/1/roll" hx-vals="{&#34;Expression&#34;:&#34;` +
+			expression + `&#34;}" hx-target="#dice-roller" hx-swap="outerHTML">` + expression + `</button>`
+	}
+	link := func(id, name string) string {
+		return `<a href="/character/` + id + `" class="character-link">` + name + `</a>`
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"roll", "[[1d20+5]]", "<p>" + roll("1d20+5") + "</p>\n"},
+		{"roll with spaces", "[[ 1d20 ]]", "<p>" + roll("1d20") + "</p>\n"},
+		{"roll with reference", "[[1d20+@FOR]]", "<p>" + roll("1d20+@FOR") + "</p>\n"},
+		{"character by id", "[[char:2]]", "<p>" + link("2", "Nimue") + "</p>\n"},
+		{"character by id, any case", "[[CHAR: 1]]", "<p>" + link("1", "Aldric") + "</p>\n"},
+		{"mention", "@Nimue, @nimue.", "<p>" + link("2", "Nimue") + ", " + link("2", "Nimue") + ".</p>\n"},
+		{"accented mention", "@Éloïse", "<p>" + link("5", "Éloïse") + "</p>\n"},
+		{"mention in emphasis", "*@Nimue* **[[1d8]]**", "<p><em>" + link("2", "Nimue") + "</em> <strong>" + roll("1d8") + "</strong></p>\n"},
+		{"escaped name", "[[char:6]]", "<p>" + link("6", "&lt;b&gt;x&lt;/b&gt;&#34;") + "</p>\n"},
+
+		{"unterminated roll", "[[1d20", "<p>[[1d20</p>\n"},
+		{"single closing bracket", "[[1d20]", "<p>[[1d20]</p>\n"},
+		{"roll across lines", "[[1d20\n]]", "<p>[[1d20<br>\n]]</p>\n"},
+		{"empty marker", "[[]]", "<p>[[]]</p>\n"},
+		{"nested roll", "[[ [[1d6]] ]]", "<p>[[ " + roll("1d6") + " ]]</p>\n"},
+		{"nested unterminated roll", "[[1d20 [[2d6]]", "<p>[[1d20 " + roll("2d6") + "</p>\n"},
+		{"too long", "[[1d20" + strings.Repeat("+1", 60) + "]]", "<p>[[1d20" + strings.Repeat("+1", 60) + "]]</p>\n"},
+
+		{"in code span", "`[[1d20]] @Nimue`", "<p><code>[[1d20]] @Nimue</code></p>\n"},
+		{"in indented code", "    [[1d20]] @Nimue\n", "<pre><code>[[1d20]] @Nimue\n</code></pre>\n"},
+		{"in fenced code", "```\n[[1d20]] @Nimue\n```", "<pre><code>[[1d20]] @Nimue\n</code></pre>\n"},
+		{"in link text", "[go [[1d20]] @Nimue](/x)", `<p><a href="/x">go [[1d20]] @Nimue</a></p>` + "\n"},
+		{"in image alt", "![[[1d20]] @Nimue](/x.png)", `<p><img src="/x.png" alt="[[1d20]] @Nimue"></p>` + "\n"},
+
+		{"escaped roll", `\[[1d20]]`, "<p>[[1d20]]</p>\n"},
+		{"escaped inner bracket", `[\[1d20]]`, "<p>[[1d20]]</p>\n"},
+		{"escaped mention", `\@Nimue`, "<p>@Nimue</p>\n"},
+		{"mention in a word", "a@Nimue", "<p>a@Nimue</p>\n"},
+		{"lone at sign", "@ @-x", "<p>@ @-x</p>\n"},
+
+		{"no dice", "[[5]]", "<p>[[5]]</p>\n"},
+		{"not an expression", "[[nope]]", "<p>[[nope]]</p>\n"},
+		{"zero dice", "[[0d6]]", "<p>[[0d6]]</p>\n"},
+		{"unknown reference", "[[1d20+@XYZ]]", "<p>[[1d20+@XYZ]]</p>\n"},
+		{"missing character id", "[[char:99]]", "<p>[[char:99]]</p>\n"},
+		{"invalid character id", "[[char:-1]] [[char:x]] [[char:]]", "<p>[[char:-1]] [[char:x]] [[char:]]</p>\n"},
+		{"missing name", "@Unknown", "<p>@Unknown</p>\n"},
+		{"ambiguous name", "@Bob", "<p>@Bob</p>\n"},
+	}
+
+	renderer := NewRenderer(DefaultPolicy())
+	context := &Context{
+		RollURL: "/character/1/roll",
+		Characters: []Character{
+			{ID: 1, Name: "Aldric"},
+			{ID: 2, Name: "Nimue"},
+			{ID: 3, Name: "Bob"},
+			{ID: 4, Name: "bob"},
+			{ID: 5, Name: "Éloïse"},
+			{ID: 6, Name: `<b>x</b>"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(renderer.RenderWith(tt.input, context))
+			if got != tt.want {
+				t.Errorf("RenderWith(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWithoutContext(t *testing.T) {
+	input := "[[1d20]] @Nimue [[char:1]]"
+	want := "<p>[[1d20]] @Nimue [[char:1]]</p>\n"
+
+	if got := string(NewRenderer(DefaultPolicy()).Render(input)); got != want {
+		t.Errorf("Render(%q) = %q, want %q", input, got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package markdown
+
+import (
+	"html/template"
+
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+)
+
+const extensions = parser.CommonExtensions | parser.AutoHeadingIDs | parser.NoEmptyLineBeforeBlock | parser.HardLineBreak
+
+// Renderer turns user Markdown into HTML that is safe to embed in a page.
+type Renderer struct {
+	Policy *Policy
+}
+
+func NewRenderer(policy *Policy) *Renderer {
+	return &Renderer{Policy: policy}
+}
+
+func (r *Renderer) Render(md string) template.HTML {
+	return r.RenderWith(md, nil)
+}
+
+// RenderWith renders md with the note extensions enabled by context. The
+// HTML they generate is added once the user content has been sanitized.
+func (r *Renderer) RenderWith(md string, context *Context) template.HTML {
+	p := parser.NewWithExtensions(extensions)
+	registerExtensions(p)
+
+	ext := &extensionRenderer{context: context}
+
+	renderer := html.NewRenderer(html.RendererOptions{
+		Flags:           html.CommonFlags,
+		HeadingIDPrefix: r.Policy.IDPrefix,
+		RenderNodeHook:  ext.renderNode,
+	})
+
+	output := markdown.ToHTML([]byte(stripPlaceholders(md)), p, renderer)
+
+	return template.HTML(ext.replace(r.Policy.Sanitize(string(output))))
+}
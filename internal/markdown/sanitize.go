@@ -0,0 +1,424 @@
+package markdown
+
+import (
+	"html"
+	"slices"
+	"strings"
+)
+
+// Policy is an allowlist of the HTML that survives sanitizing. Anything not
+// listed is dropped; text is always re-escaped.
+type Policy struct {
+	// Elements maps the allowed tags to their allowed attributes.
+	Elements map[string][]string
+	// URLAttributes are checked against URLSchemes. Relative URLs are allowed.
+	URLAttributes []string
+	URLSchemes    []string
+	// Classes and ClassPrefixes list the class names kept on "class" attributes.
+	Classes       []string
+	ClassPrefixes []string
+	// IDPrefix is required on "id" attributes, so that user content cannot
+	// shadow the ids the page relies on.
+	IDPrefix string
+	// ExternalLinkRel and ExternalLinkTarget are set on links to other sites.
+	ExternalLinkRel    string
+	ExternalLinkTarget string
+}
+
+// DefaultPolicy allows the HTML produced from Markdown.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Elements: map[string][]string{
+			"a":          {"href", "title"},
+			"abbr":       {"title"},
+			"b":          nil,
+			"blockquote": nil,
+			"br":         nil,
+			"code":       {"class"},
+			"dd":         nil,
+			"del":        nil,
+			"details":    nil,
+			"div":        nil,
+			"dl":         nil,
+			"dt":         nil,
+			"em":         nil,
+			"h1":         {"id"},
+			"h2":         {"id"},
+			"h3":         {"id"},
+			"h4":         {"id"},
+			"h5":         {"id"},
+			"h6":         {"id"},
+			"hr":         nil,
+			"i":          nil,
+			"img":        {"src", "alt", "title"},
+			"ins":        nil,
+			"kbd":        nil,
+			"li":         nil,
+			"mark":       nil,
+			"ol":         {"start"},
+			"p":          nil,
+			"pre":        nil,
+			"s":          nil,
+			"small":      nil,
+			"span":       {"class"},
+			"strong":     nil,
+			"sub":        nil,
+			"summary":    nil,
+			"sup":        nil,
+			"table":      nil,
+			"tbody":      nil,
+			"td":         {"align"},
+			"tfoot":      nil,
+			"th":         {"align"},
+			"thead":      nil,
+			"tr":         nil,
+			"u":          nil,
+			"ul":         nil,
+		},
+		URLAttributes:      []string{"href", "src"},
+		URLSchemes:         []string{"http", "https", "mailto"},
+		Classes:            []string{"math", "inline", "display"},
+		ClassPrefixes:      []string{"language-"},
+		IDPrefix:           "md-",
+		ExternalLinkRel:    "noopener noreferrer",
+		ExternalLinkTarget: "_blank",
+	}
+}
+
+// droppedWithContent are elements whose content is removed along with them.
+var droppedWithContent = []string{
+	"embed", "iframe", "math", "noembed", "noframes", "noscript", "object",
+	"script", "style", "svg", "template", "textarea", "title", "xmp",
+}
+
+var voidElements = []string{"br", "hr", "img"}
+
+// Sanitize rewrites s keeping only the allowed elements and attributes. The
+// output is rebuilt from what was parsed rather than copied, and open tags
+// are balanced.
+func (p *Policy) Sanitize(s string) string {
+	var (
+		sb   strings.Builder
+		open []string
+	)
+
+	for s != "" {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			writeText(&sb, s)
+			break
+		}
+
+		writeText(&sb, s[:i])
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			s = skipPast(s[4:], "-->")
+			continue
+		case strings.HasPrefix(s, "<!"), strings.HasPrefix(s, "<?"):
+			s = skipPast(s[2:], ">")
+			continue
+		}
+
+		t, rest, ok := parseTag(s)
+		if !ok {
+			sb.WriteString("&lt;")
+			s = s[1:]
+
+			continue
+		}
+
+		s = rest
+
+		if t.closing {
+			if slices.Contains(voidElements, t.name) {
+				continue
+			}
+
+			j := slices.Index(open, t.name)
+			if j < 0 {
+				continue
+			}
+
+			for k := len(open) - 1; k >= j; k-- {
+				sb.WriteString("</" + open[k] + ">")
+			}
+
+			open = open[:j]
+
+			continue
+		}
+
+		if slices.Contains(droppedWithContent, t.name) {
+			s = skipPastFold(s, "</"+t.name)
+			s = skipPast(s, ">")
+
+			continue
+		}
+
+		if _, allowed := p.Elements[t.name]; !allowed {
+			continue
+		}
+
+		sb.WriteString(p.openingTag(t))
+
+		if !slices.Contains(voidElements, t.name) {
+			open = append(open, t.name)
+		}
+	}
+
+	for k := len(open) - 1; k >= 0; k-- {
+		sb.WriteString("</" + open[k] + ">")
+	}
+
+	return sb.String()
+}
+
+func (p *Policy) openingTag(t tag) string {
+	var sb strings.Builder
+
+	sb.WriteString("<" + t.name)
+
+	external := false
+	allowed := p.Elements[t.name]
+
+	for _, a := range t.attrs {
+		if !slices.Contains(allowed, a.name) {
+			continue
+		}
+
+		value, ok := p.attributeValue(t.name, a.name, a.value)
+		if !ok {
+			continue
+		}
+
+		if t.name == "a" && a.name == "href" && isExternalURL(value) {
+			external = true
+		}
+
+		writeAttribute(&sb, a.name, value)
+	}
+
+	if external {
+		if p.ExternalLinkRel != "" {
+			writeAttribute(&sb, "rel", p.ExternalLinkRel)
+		}
+
+		if p.ExternalLinkTarget != "" {
+			writeAttribute(&sb, "target", p.ExternalLinkTarget)
+		}
+	}
+
+	sb.WriteString(">")
+
+	return sb.String()
+}
+
+func (p *Policy) attributeValue(element, name, value string) (string, bool) {
+	switch {
+	case slices.Contains(p.URLAttributes, name):
+		return value, p.allowedURL(value)
+	case name == "id":
+		return value, p.IDPrefix != "" && strings.HasPrefix(value, p.IDPrefix)
+	case name == "class":
+		var classes []string
+
+		for _, class := range strings.Fields(value) {
+			if p.allowedClass(class) {
+				classes = append(classes, class)
+			}
+		}
+
+		return strings.Join(classes, " "), len(classes) > 0
+	case name == "align":
+		return value, slices.Contains([]string{"left", "center", "right"}, value)
+	case name == "start":
+		for _, r := range value {
+			if r < '0' || r > '9' {
+				return "", false
+			}
+		}
+
+		return value, value != ""
+	}
+
+	return value, true
+}
+
+func (p *Policy) allowedClass(class string) bool {
+	if slices.Contains(p.Classes, class) {
+		return true
+	}
+
+	for _, prefix := range p.ClassPrefixes {
+		if strings.HasPrefix(class, prefix) && len(class) > len(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowedURL accepts relative URLs and the allowed schemes. Browsers ignore
+// control characters and whitespace in schemes, so they are removed before
+// looking for one.
+func (p *Policy) allowedURL(value string) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+
+		return r
+	}, value)
+
+	i := strings.IndexAny(cleaned, ":/?#")
+	if i < 0 || cleaned[i] != ':' {
+		return true
+	}
+
+	return slices.Contains(p.URLSchemes, strings.ToLower(cleaned[:i]))
+}
+
+func isExternalURL(value string) bool {
+	lower := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(value), `\`, "/"))
+
+	return strings.HasPrefix(lower, "http:") || strings.HasPrefix(lower, "https:") || strings.HasPrefix(lower, "//")
+}
+
+func writeText(sb *strings.Builder, s string) {
+	sb.WriteString(html.EscapeString(html.UnescapeString(s)))
+}
+
+func writeAttribute(sb *strings.Builder, name, value string) {
+	sb.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+}
+
+func skipPast(s, marker string) string {
+	i := strings.Index(s, marker)
+	if i < 0 {
+		return ""
+	}
+
+	return s[i+len(marker):]
+}
+
+func skipPastFold(s, marker string) string {
+	i := strings.Index(strings.ToLower(s), marker)
+	if i < 0 {
+		return ""
+	}
+
+	return s[i+len(marker):]
+}
+
+type attribute struct {
+	name  string
+	value string
+}
+
+type tag struct {
+	name    string
+	closing bool
+	attrs   []attribute
+}
+
+// parseTag reads a start or end tag at the beginning of s, returning the
+// input that follows it.
+func parseTag(s string) (tag, string, bool) {
+	var t tag
+
+	pos := 1
+
+	if pos < len(s) && s[pos] == '/' {
+		t.closing = true
+		pos++
+	}
+
+	start := pos
+	for pos < len(s) && isNameByte(s[pos], pos == start) {
+		pos++
+	}
+
+	if pos == start {
+		return tag{}, s, false
+	}
+
+	t.name = strings.ToLower(s[start:pos])
+
+	for {
+		for pos < len(s) && (isSpace(s[pos]) || s[pos] == '/') {
+			pos++
+		}
+
+		if pos >= len(s) {
+			return tag{}, s, false
+		}
+
+		if s[pos] == '>' {
+			return t, s[pos+1:], true
+		}
+
+		start := pos
+		for pos < len(s) && !isSpace(s[pos]) && !strings.ContainsRune("/>=", rune(s[pos])) {
+			pos++
+		}
+
+		a := attribute{name: strings.ToLower(s[start:pos])}
+
+		for pos < len(s) && isSpace(s[pos]) {
+			pos++
+		}
+
+		if pos < len(s) && s[pos] == '=' {
+			pos++
+
+			for pos < len(s) && isSpace(s[pos]) {
+				pos++
+			}
+
+			if pos >= len(s) {
+				return tag{}, s, false
+			}
+
+			switch quote := s[pos]; quote {
+			case '"', '\'':
+				end := strings.IndexByte(s[pos+1:], quote)
+				if end < 0 {
+					return tag{}, s, false
+				}
+
+				a.value = s[pos+1 : pos+1+end]
+				pos += end + 2
+			default:
+				start := pos
+				for pos < len(s) && !isSpace(s[pos]) && s[pos] != '>' {
+					pos++
+				}
+
+				a.value = s[start:pos]
+			}
+
+			a.value = html.UnescapeString(a.value)
+		}
+
+		if a.name != "" && !slices.ContainsFunc(t.attrs, func(other attribute) bool { return other.name == a.name }) {
+			t.attrs = append(t.attrs, a)
+		}
+	}
+}
+
+func isNameByte(b byte, first bool) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z':
+		return true
+	case b >= '0' && b <= '9':
+		return !first
+	}
+
+	return false
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
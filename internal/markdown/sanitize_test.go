@@ -0,0 +1,86 @@
+package markdown
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"javascript URL", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"mixed case scheme", `<a href="JaVaScRiPt:alert(1)">x</a>`, `<a>x</a>`},
+		{"decimal entity", `<a href="&#106;avascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"hex entities", `<a href="&#x6A;avascript&#x3A;alert(1)">x</a>`, `<a>x</a>`},
+		{"tab entity", `<a href="java&#x09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{"newline entity", `<a href="jav&#x0A;ascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"leading space", `<a href=" javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"raw tab", "<a href=\"java\tscript:alert(1)\">x</a>", `<a>x</a>`},
+		{"vbscript URL", `<a href="vbscript:msgbox(1)">x</a>`, `<a>x</a>`},
+		{"data URL link", `<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`, `<a>x</a>`},
+		{"data URL image", `<img src="data:image/svg+xml,<svg onload=alert(1)>">`, `<img>`},
+		{"javascript image", `<img src="javascript:alert(1)">`, `<img>`},
+		{"external link", `<a href="https://example.org">x</a>`, `<a href="https://example.org" rel="noopener noreferrer" target="_blank">x</a>`},
+		{"relative link", `<a href="/character/1">x</a>`, `<a href="/character/1">x</a>`},
+		{"mailto link", `<a href="mailto:a@b.c">x</a>`, `<a href="mailto:a@b.c">x</a>`},
+		{"script", `<script>alert(1)</script>after`, `after`},
+		{"uppercase script", `<SCRIPT SRC=//x.js></SCRIPT>after`, `after`},
+		{"script in svg", `<svg><script>alert(1)</script></svg>after`, `after`},
+		{"unclosed svg", `<svg onload=alert(1)>after`, ``},
+		{"noscript breakout", `<noscript><p title="</noscript><img src=x onerror=alert(1)>"></noscript>`, `<img src="x">&#34;&gt;`},
+		{"split script", `<scr<script>ipt>alert(1)</script>`, `ipt&gt;alert(1)`},
+		{"nested brackets", `<<script>script>alert(1)<</script>/script>`, `&lt;/script&gt;`},
+		{"onerror", `<img src=x onerror=alert(1)>`, `<img src="x">`},
+		{"onclick", `<p onclick="alert(1)">x</p>`, `<p>x</p>`},
+		{"onmouseover", `<a href="https://ok" onmouseover="alert(1)">ok</a>`, `<a href="https://ok" rel="noopener noreferrer" target="_blank">ok</a>`},
+		{"attribute without space", `<img src="x" alt="a"onerror="alert(1)">`, `<img src="x" alt="a">`},
+		{"slashes as separators", `<img/src/onerror=alert(1)>`, `<img src="">`},
+		{"markup in attribute", `<a href="x" title='a"><script>alert(1)</script>'>x</a>`, `<a href="x" title="a&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">x</a>`},
+		{"unprefixed id", `<div id="notes">x</div>`, `<div>x</div>`},
+		{"unknown class", `<span class="position-fixed math">x</span>`, `<span class="math">x</span>`},
+		{"comment", `<!--<script>alert(1)//-->after`, `after`},
+		{"unclosed tags", `<div><b>unclosed`, `<div><b>unclosed</b></div>`},
+	}
+
+	policy := DefaultPolicy()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Sanitize(tt.input)
+			if got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// Placeholders in the input must not pull in the HTML generated by the
+// extensions.
+func TestRenderPlaceholderInjection(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"alone", "\ue0000\ue001", "<p>0</p>\n"},
+		{"in HTML", "<p>\ue0000\ue001</p>", "<p><p>0</p></p>\n"},
+		{
+			"next to a roll",
+			"[[1d20]] \ue0000\ue001",
+			`<p><button type="button" class="roll-inline" hx-post="/character/1/roll" hx-vals="{&#34;Expression&#34;:&#34;1d20&#34;}" hx-target="#dice-roller" hx-swap="outerHTML">1d20</button> 0</p>` + "\n",
+		},
+		{"next to a mention", "@Aldric \ue0000\ue001", `<p><a href="/character/1" class="character-link">Aldric</a> 0</p>` + "\n"},
+	}
+
+	renderer := NewRenderer(DefaultPolicy())
+	context := &Context{RollURL: "/character/1/roll", Characters: []Character{{ID: 1, Name: "Aldric"}}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(renderer.RenderWith(tt.input, context))
+			if got != tt.want {
+				t.Errorf("RenderWith(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,29 +2,68 @@ package password
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+)
+
+// PBKDF2Iterations matches the PBKDF2PasswordHasher default of Django 5.0.
+// Hashes using fewer iterations are upgraded on login.
+const PBKDF2Iterations = 720_000
 
-	"golang.org/x/crypto/bcrypt"
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
 )
 
+// Hash returns a hash in Django's pbkdf2_sha256 format, so that accounts
+// created here can also log into the Django app.
 func Hash(plaintextPassword string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	salt, err := randomSalt()
 	if err != nil {
 		return "", err
 	}
 
-	return string(hashedPassword), nil
+	return encodePBKDF2(algorithmPBKDF2SHA256, plaintextPassword, salt, PBKDF2Iterations)
 }
 
+// Matches verifies plaintextPassword against any of the Django hasher formats
+// (pbkdf2_sha256, pbkdf2_sha1, argon2, bcrypt_sha256, bcrypt) as well as the
+// bare bcrypt hashes written by earlier versions of this application.
 func Matches(plaintextPassword, hashedPassword string) (bool, error) {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(plaintextPassword))
+	if hashedPassword == "" || strings.HasPrefix(hashedPassword, unusablePasswordPrefix) {
+		return false, nil
+	}
+
+	algorithm, _, _ := strings.Cut(hashedPassword, "$")
+
+	switch algorithm {
+	case algorithmPBKDF2SHA256, algorithmPBKDF2SHA1:
+		return matchesPBKDF2(plaintextPassword, hashedPassword)
+	case algorithmArgon2:
+		return matchesArgon2(plaintextPassword, hashedPassword)
+	case algorithmBcryptSHA256:
+		return matchesBcryptSHA256(plaintextPassword, hashedPassword)
+	case algorithmBcrypt:
+		return matchesBcrypt(plaintextPassword, strings.TrimPrefix(hashedPassword, algorithmBcrypt+"$"))
+	case "":
+		return matchesBcrypt(plaintextPassword, hashedPassword)
+	}
+
+	return false, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algorithm)
+}
+
+// NeedsRehash reports whether a hash that was successfully matched should be
+// replaced by a fresh one from Hash.
+func NeedsRehash(hashedPassword string) bool {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 4 || parts[0] != algorithmPBKDF2SHA256 {
+		return true
+	}
+
+	iterations, err := parseIterations(parts[1])
 	if err != nil {
-		switch {
-		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
-			return false, nil
-		default:
-			return false, err
-		}
+		return true
 	}
 
-	return true, nil
+	return iterations < PBKDF2Iterations
 }
@@ -0,0 +1,163 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // required by Django's pbkdf2_sha1 hasher
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	algorithmPBKDF2SHA256 = "pbkdf2_sha256"
+	algorithmPBKDF2SHA1   = "pbkdf2_sha1"
+	algorithmArgon2       = "argon2"
+	algorithmBcryptSHA256 = "bcrypt_sha256"
+	algorithmBcrypt       = "bcrypt"
+
+	unusablePasswordPrefix = "!"
+
+	saltLength = 22
+	saltChars  = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+func randomSalt() (string, error) {
+	var sb strings.Builder
+
+	limit := big.NewInt(int64(len(saltChars)))
+
+	for i := 0; i < saltLength; i++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteByte(saltChars[n.Int64()])
+	}
+
+	return sb.String(), nil
+}
+
+func parseIterations(s string) (int, error) {
+	iterations, err := strconv.Atoi(s)
+	if err != nil || iterations <= 0 {
+		return 0, ErrMalformedHash
+	}
+
+	return iterations, nil
+}
+
+func pbkdf2Digest(algorithm string) func() hash.Hash {
+	if algorithm == algorithmPBKDF2SHA1 {
+		return sha1.New
+	}
+
+	return sha256.New
+}
+
+func encodePBKDF2(algorithm, plaintextPassword, salt string, iterations int) (string, error) {
+	if strings.Contains(salt, "$") {
+		return "", ErrMalformedHash
+	}
+
+	digest := pbkdf2Digest(algorithm)
+	key := pbkdf2.Key([]byte(plaintextPassword), []byte(salt), iterations, digest().Size(), digest)
+
+	return fmt.Sprintf("%s$%d$%s$%s", algorithm, iterations, salt, base64.StdEncoding.EncodeToString(key)), nil
+}
+
+func matchesPBKDF2(plaintextPassword, hashedPassword string) (bool, error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 4 {
+		return false, ErrMalformedHash
+	}
+
+	iterations, err := parseIterations(parts[1])
+	if err != nil {
+		return false, err
+	}
+
+	expected, err := encodePBKDF2(parts[0], plaintextPassword, parts[2], iterations)
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(hashedPassword)) == 1, nil
+}
+
+// matchesArgon2 handles Django's "argon2$argon2id$v=19$m=...,t=...,p=...$salt$hash"
+// format.
+func matchesArgon2(plaintextPassword, hashedPassword string) (bool, error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[2] != fmt.Sprintf("v=%d", argon2.Version) {
+		return false, ErrMalformedHash
+	}
+
+	var memory, time uint32
+	var threads uint8
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads)
+	if err != nil {
+		return false, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrMalformedHash
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, ErrMalformedHash
+	}
+
+	// argon2 panics on zero passes or threads, and an empty hash would
+	// match any password.
+	if time < 1 || threads < 1 || len(expected) == 0 {
+		return false, nil
+	}
+
+	var key []byte
+
+	switch parts[1] {
+	case "argon2id":
+		key = argon2.IDKey([]byte(plaintextPassword), salt, time, memory, threads, uint32(len(expected)))
+	case "argon2i":
+		key = argon2.Key([]byte(plaintextPassword), salt, time, memory, threads, uint32(len(expected)))
+	default:
+		return false, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, parts[1])
+	}
+
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+func matchesBcryptSHA256(plaintextPassword, hashedPassword string) (bool, error) {
+	digest := sha256.Sum256([]byte(plaintextPassword))
+
+	return matchesBcrypt(hex.EncodeToString(digest[:]), strings.TrimPrefix(hashedPassword, algorithmBcryptSHA256+"$"))
+}
+
+func matchesBcrypt(plaintextPassword, hashedPassword string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(plaintextPassword))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	return true, nil
+}
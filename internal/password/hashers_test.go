@@ -0,0 +1,127 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestMatchesArgon2(t *testing.T) {
+	salt := []byte("somesalt")
+	key := argon2.IDKey([]byte("secret"), salt, 2, 64, 1, 32)
+
+	hash := func(params, key string) string {
+		return fmt.Sprintf("argon2$argon2id$v=19$%s$%s$%s", params, base64.RawStdEncoding.EncodeToString(salt), key)
+	}
+
+	valid := base64.RawStdEncoding.EncodeToString(key)
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+	}{
+		{"matching", "secret", hash("m=64,t=2,p=1", valid), true},
+		{"wrong password", "wrong", hash("m=64,t=2,p=1", valid), false},
+		{"zero passes", "secret", hash("m=64,t=0,p=1", valid), false},
+		{"zero threads", "secret", hash("m=64,t=2,p=0", valid), false},
+		{"empty hash", "secret", hash("m=64,t=2,p=1", ""), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Matches(tt.password, tt.hash)
+			if err != nil {
+				t.Fatalf("Matches() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesDjangoHashes(t *testing.T) {
+	// The pbkdf2 hashes come from Django's tests/auth_tests/test_hashers.py.
+	// The bcrypt hashes are written the way Django's hashers write them, and
+	// the bare one is an OpenBSD test vector.
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+		wantErr  error
+	}{
+		{"pbkdf2_sha256", "lètmein", "pbkdf2_sha256$720000$seasalt$eDupbcisD1UuIiou3hMuMu8oe/XwnpDw45r6AA5iv0E=", true, nil},
+		{"pbkdf2_sha256 wrong password", "letmein", "pbkdf2_sha256$720000$seasalt$eDupbcisD1UuIiou3hMuMu8oe/XwnpDw45r6AA5iv0E=", false, nil},
+		{"pbkdf2_sha1", "lètmein", "pbkdf2_sha1$720000$seasalt2$2DDbzziqCtfldrRSNAaF8oA9OMw=", true, nil},
+		{"pbkdf2_sha1 wrong password", "letmein", "pbkdf2_sha1$720000$seasalt2$2DDbzziqCtfldrRSNAaF8oA9OMw=", false, nil},
+		{"bcrypt_sha256", "lètmein", "bcrypt_sha256$$2b$04$7HIb6BR0HVyEQRQSNMZwIuaMRDEYO.oQF6sfyKuUam2YP18BecDie", true, nil},
+		{"bcrypt_sha256 wrong password", "letmein", "bcrypt_sha256$$2b$04$7HIb6BR0HVyEQRQSNMZwIuaMRDEYO.oQF6sfyKuUam2YP18BecDie", false, nil},
+		{"bcrypt", "lètmein", "bcrypt$$2b$04$U5hApVTaOX/aIAtA3yaqeuX22qhxbtkqwt/ykbg2tvU.R.PMFCpuS", true, nil},
+		{"bcrypt wrong password", "letmein", "bcrypt$$2b$04$U5hApVTaOX/aIAtA3yaqeuX22qhxbtkqwt/ykbg2tvU.R.PMFCpuS", false, nil},
+		{"bare bcrypt", "U*U", "$2a$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW", true, nil},
+		{"unusable password", "", "!Kq5T0BDAzbcJtK8t9U5xw6vYfXzSjZ5rZKbcXzQ3", false, nil},
+		{"malformed iterations", "lètmein", "pbkdf2_sha256$many$seasalt$eDupbcisD1UuIiou3hMuMu8oe/XwnpDw45r6AA5iv0E=", false, ErrMalformedHash},
+		{"unknown algorithm", "lètmein", "md5$seasalt$f5ec8f2b8d3c4eb0e5b0cfa3a6b1f0a2", false, ErrUnknownAlgorithm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Matches(tt.password, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Matches() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	hash, err := Hash("lètmein")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	ok, err := Matches("lètmein", hash)
+	if err != nil || !ok {
+		t.Errorf("Matches(Hash()) = %v, %v, want true", ok, err)
+	}
+
+	if NeedsRehash(hash) {
+		t.Errorf("NeedsRehash(%q) = true, want false", hash)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"current pbkdf2_sha256", "pbkdf2_sha256$720000$seasalt$eDupbcisD1UuIiou3hMuMu8oe/XwnpDw45r6AA5iv0E=", false},
+		{"more iterations", "pbkdf2_sha256$1000000$seasalt$hash", false},
+		{"fewer iterations", "pbkdf2_sha256$600000$seasalt$hash", true},
+		{"pbkdf2_sha1", "pbkdf2_sha1$720000$seasalt2$2DDbzziqCtfldrRSNAaF8oA9OMw=", true},
+		{"argon2", "argon2$argon2id$v=19$m=102400,t=2,p=8$c29tZXNhbHQ$hash", true},
+		{"bcrypt_sha256", "bcrypt_sha256$$2b$04$7HIb6BR0HVyEQRQSNMZwIuaMRDEYO.oQF6sfyKuUam2YP18BecDie", true},
+		{"bare bcrypt", "$2a$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW", true},
+		{"malformed iterations", "pbkdf2_sha256$many$seasalt$hash", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash(%q) = %v, want %v", tt.hash, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+)
+
+// InitiativeDie is added to the initiative score at the start of a fight,
+// following the variable initiative rule, so that equal scores do not always
+// play in the same order.
+const InitiativeDie = 6
+
+// RollInitiative rolls the initiative of a combatant with the given score.
+func RollInitiative(roller *dice.Roller, initiative int) (int, error) {
+	result, err := roller.RollString(fmt.Sprintf("1d%d", InitiativeDie), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return initiative + result.Total, nil
+}
+
+// RoundsPerMinute converts effect durations in minutes to rounds, a round
+// lasting about ten seconds.
+const RoundsPerMinute = 6
+
+type AttackKind string
+
+const (
+	AttackMelee  AttackKind = "melee"
+	AttackRanged AttackKind = "ranged"
+	AttackMagic  AttackKind = "magic"
+)
+
+var AttackKinds = []AttackKind{AttackMelee, AttackRanged, AttackMagic}
+
+func (s Stats) AttackBonus(kind AttackKind) int {
+	switch kind {
+	case AttackMelee:
+		return s.AttackMelee
+	case AttackRanged:
+		return s.AttackRanged
+	case AttackMagic:
+		return s.AttackMagic
+	}
+
+	return 0
+}
+
+// AttackDie is rolled for attacks. A natural roll of at least the critical
+// threshold always hits and doubles the damage; a natural 1 always misses.
+const (
+	AttackDie         = 20
+	CriticalThreshold = 20
+)
+
+type Attack struct {
+	Roll      *dice.Result `json:"roll"`
+	Natural   int          `json:"natural"`
+	Defense   int          `json:"defense"`
+	Hit       bool         `json:"hit"`
+	Critical  bool         `json:"critical"`
+	Fumble    bool         `json:"fumble"`
+	Damage    *dice.Result `json:"damage,omitempty"`
+	Reduction int          `json:"reduction"`
+	Dealt     int          `json:"dealt"`
+}
+
+// ResolveAttack rolls the attack against defense and, when it hits, the
+// damage expression with the references of the attacker. The damage is
+// doubled on a critical, then the damage reduction of the target is taken off.
+func ResolveAttack(roller *dice.Roller, refs map[string]int, bonus, critical, defense int, damage string, reduction int) (*Attack, error) {
+	damageExpression, err := ParseDice(damage)
+	if err != nil {
+		return nil, err
+	}
+
+	roll, err := roller.RollString(fmt.Sprintf("1d%d%+d", AttackDie, bonus), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	attack := &Attack{
+		Roll:      roll,
+		Natural:   roll.Terms[0].Dice[0].Value,
+		Defense:   defense,
+		Reduction: reduction,
+	}
+
+	attack.Fumble = attack.Natural == 1
+	attack.Critical = !attack.Fumble && attack.Natural >= critical
+	attack.Hit = attack.Critical || (!attack.Fumble && roll.Total >= defense)
+
+	if !attack.Hit {
+		return attack, nil
+	}
+
+	attack.Damage, err = roller.Roll(damageExpression, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	dealt := attack.Damage.Total
+	if attack.Critical {
+		dealt *= 2
+	}
+
+	attack.Dealt = max(0, dealt-reduction)
+
+	return attack, nil
+}
+
+// DamageReduction reads the damage reduction of the character, written as
+// free text on the sheet such as "2" or "3 (sauf feu)": the first number.
+func DamageReduction(character *database.Character) int {
+	text := strings.TrimLeftFunc(character.DamageReduction, func(r rune) bool { return !unicode.IsDigit(r) })
+	end := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsDigit(r) })
+
+	if end >= 0 {
+		text = text[:end]
+	}
+
+	reduction, _ := strconv.Atoi(text)
+
+	return reduction
+}
@@ -0,0 +1,136 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+)
+
+// dieSource makes a dice.Roller roll the given values in order, as
+// rand.Intn keeps the top 31 bits of Int63 and takes them modulo the number
+// of sides.
+type dieSource []int
+
+func (s *dieSource) Int63() int64 {
+	value := (*s)[0]
+	*s = (*s)[1:]
+
+	return int64(value-1) << 32
+}
+
+func (s *dieSource) Seed(int64) {}
+
+func TestResolveAttack(t *testing.T) {
+	tests := []struct {
+		name      string
+		rolls     []int
+		bonus     int
+		critical  int
+		defense   int
+		damage    string
+		reduction int
+		want      Attack
+	}{
+		{
+			name:  "natural 1 always misses",
+			rolls: []int{1}, bonus: 30, critical: 20, defense: 10, damage: "1d6",
+			want: Attack{Natural: 1, Defense: 10, Fumble: true},
+		},
+		{
+			name:  "below defense misses",
+			rolls: []int{9}, bonus: 5, critical: 20, defense: 15, damage: "1d6",
+			want: Attack{Natural: 9, Defense: 15},
+		},
+		{
+			name:  "total equal to defense hits",
+			rolls: []int{10, 4}, bonus: 5, critical: 20, defense: 15, damage: "1d6",
+			want: Attack{Natural: 10, Defense: 15, Hit: true, Dealt: 4},
+		},
+		{
+			name:  "damage references the attacker",
+			rolls: []int{15, 5}, bonus: 0, critical: 20, defense: 10, damage: "1d8+@FOR",
+			want: Attack{Natural: 15, Defense: 10, Hit: true, Dealt: 8},
+		},
+		{
+			name:  "critical range below 20 hits and doubles damage",
+			rolls: []int{19, 3}, bonus: 0, critical: 19, defense: 30, damage: "1d6",
+			want: Attack{Natural: 19, Defense: 30, Hit: true, Critical: true, Dealt: 6},
+		},
+		{
+			name:  "19 is not critical with the default range",
+			rolls: []int{19}, bonus: 0, critical: 20, defense: 30, damage: "1d6",
+			want: Attack{Natural: 19, Defense: 30},
+		},
+		{
+			name:  "reduction applies after doubling",
+			rolls: []int{20, 3}, bonus: 0, critical: 20, defense: 10, damage: "1d6", reduction: 2,
+			want: Attack{Natural: 20, Defense: 10, Hit: true, Critical: true, Reduction: 2, Dealt: 4},
+		},
+		{
+			name:  "reduction clamps at 0",
+			rolls: []int{15, 2}, bonus: 0, critical: 20, defense: 10, damage: "1d4", reduction: 5,
+			want: Attack{Natural: 15, Defense: 10, Hit: true, Reduction: 5, Dealt: 0},
+		},
+	}
+
+	refs := DiceReferences(&database.Character{
+		Level: 1, ValueStrength: 16, ValueDexterity: 10, ValueConstitution: 10,
+		ValueIntelligence: 10, ValueWisdom: 10, ValueCharisma: 10,
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := dieSource(tt.rolls)
+			roller := dice.NewRoller(&source)
+
+			got, err := ResolveAttack(roller, refs, tt.bonus, tt.critical, tt.defense, tt.damage, tt.reduction)
+			if err != nil {
+				t.Fatalf("ResolveAttack() error = %v", err)
+			}
+
+			if len(source) != 0 {
+				t.Errorf("%d dice left unrolled", len(source))
+			}
+
+			if got.Natural != tt.want.Natural || got.Defense != tt.want.Defense || got.Hit != tt.want.Hit ||
+				got.Critical != tt.want.Critical || got.Fumble != tt.want.Fumble ||
+				got.Reduction != tt.want.Reduction || got.Dealt != tt.want.Dealt {
+				t.Errorf("ResolveAttack() = %+v, want %+v", *got, tt.want)
+			}
+
+			if hasDamage := got.Damage != nil; hasDamage != tt.want.Hit {
+				t.Errorf("ResolveAttack() rolled damage = %v, want %v", hasDamage, tt.want.Hit)
+			}
+		})
+	}
+}
+
+func TestResolveAttackInvalidDamage(t *testing.T) {
+	source := dieSource{15}
+
+	_, err := ResolveAttack(dice.NewRoller(&source), nil, 0, 20, 10, "1d6+@XYZ", 0)
+	if err == nil {
+		t.Error("ResolveAttack() with an unknown reference error = nil, want an error")
+	}
+}
+
+func TestDamageReduction(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"2", 2},
+		{"3 (sauf feu)", 3},
+		{"RD 5 contre le feu", 5},
+		{"12/magie", 12},
+		{"aucune", 0},
+	}
+
+	for _, tt := range tests {
+		if got := DamageReduction(&database.Character{DamageReduction: tt.text}); got != tt.want {
+			t.Errorf("DamageReduction(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package rules
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+)
+
+// AbilityMethod is how the abilities of a new character are generated, as
+// described in the Chroniques Oubliées rules.
+type AbilityMethod string
+
+const (
+	MethodStandardArray AbilityMethod = "array"
+	MethodPointBuy      AbilityMethod = "pointbuy"
+	MethodRoll          AbilityMethod = "roll"
+)
+
+var AbilityMethods = []AbilityMethod{MethodStandardArray, MethodPointBuy, MethodRoll}
+
+// StandardArray is the fixed set of values to distribute between abilities.
+var StandardArray = []int{15, 14, 13, 12, 10, 8}
+
+// Point-buy: every ability starts at PointBuyMin and raising it costs the
+// points below, up to 18, the highest value allowed at creation. Each value
+// costs one point up to 14 and two beyond. The budget is what the standard
+// array costs, so that neither method gives a stronger character.
+const (
+	PointBuyMin    = 8
+	PointBuyMax    = 18
+	PointBuyBudget = 25
+)
+
+const (
+	StartingLevel = 1
+
+	abilityRoll = "4d6kh3"
+)
+
+var pointBuyCosts = map[int]int{8: 0, 9: 1, 10: 2, 11: 3, 12: 4, 13: 5, 14: 6, 15: 8, 16: 10, 17: 12, 18: 14}
+
+// PointBuyCost returns the points spent on the values, or an error when a
+// value is out of the point-buy range.
+func PointBuyCost(values map[Ability]int) (int, error) {
+	total := 0
+
+	for _, ability := range Abilities {
+		cost, ok := pointBuyCosts[values[ability]]
+		if !ok {
+			return 0, fmt.Errorf("%s must be between %d and %d", ability, PointBuyMin, PointBuyMax)
+		}
+
+		total += cost
+	}
+
+	return total, nil
+}
+
+// IsAssignment reports whether the values give each ability one value of
+// pool, using each value of pool once.
+func IsAssignment(values map[Ability]int, pool []int) bool {
+	if len(pool) != len(Abilities) {
+		return false
+	}
+
+	assigned := make([]int, 0, len(Abilities))
+	for _, ability := range Abilities {
+		assigned = append(assigned, values[ability])
+	}
+
+	sortedPool := slices.Clone(pool)
+	slices.Sort(sortedPool)
+	slices.Sort(assigned)
+
+	return slices.Equal(assigned, sortedPool)
+}
+
+// RollAbilityScores rolls 4d6 and drops the lowest die, once per ability.
+func RollAbilityScores(roller *dice.Roller) ([]int, error) {
+	scores := make([]int, 0, len(Abilities))
+
+	for range Abilities {
+		result, err := roller.RollString(abilityRoll, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		scores = append(scores, result.Total)
+	}
+
+	return scores, nil
+}
+
+// StartingHealth is the health of a level one character: the maximum of the
+// profile's life die plus the constitution modifier.
+func StartingHealth(lifeDice, constitution int) int {
+	return max(1, lifeDice+Modifier(constitution))
+}
@@ -0,0 +1,63 @@
+package rules
+
+import (
+	"testing"
+)
+
+func TestPointBuyCost(t *testing.T) {
+	scores := func(values ...int) map[Ability]int {
+		m := map[Ability]int{}
+		for i, ability := range Abilities {
+			m[ability] = values[i]
+		}
+
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		values  map[Ability]int
+		want    int
+		fits    bool
+		wantErr bool
+	}{
+		{"all at minimum", scores(8, 8, 8, 8, 8, 8), 0, true, false},
+		{"standard array is exactly the budget", scores(15, 14, 13, 12, 10, 8), 25, true, false},
+		{"one point over budget", scores(15, 14, 13, 12, 11, 8), 26, false, false},
+		{"maximum value", scores(18, 13, 11, 10, 8, 8), 24, true, false},
+		{"below minimum", scores(7, 14, 13, 12, 10, 8), 0, false, true},
+		{"above maximum", scores(19, 8, 8, 8, 8, 8), 0, false, true},
+		{"missing ability", map[Ability]int{Strength: 10}, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PointBuyCost(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PointBuyCost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("PointBuyCost() = %d, want %d", got, tt.want)
+			}
+
+			if fits := err == nil && got <= PointBuyBudget; fits != tt.fits {
+				t.Errorf("fits the budget = %v, want %v", fits, tt.fits)
+			}
+		})
+	}
+}
+
+func TestIsAssignment(t *testing.T) {
+	values := map[Ability]int{Strength: 8, Dexterity: 15, Constitution: 14, Intelligence: 10, Wisdom: 12, Charisma: 13}
+
+	if !IsAssignment(values, StandardArray) {
+		t.Error("IsAssignment() = false for a shuffled standard array, want true")
+	}
+
+	values[Charisma] = 15
+
+	if IsAssignment(values, StandardArray) {
+		t.Error("IsAssignment() = true with a value used twice, want false")
+	}
+}
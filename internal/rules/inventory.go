@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+)
+
+// Coin values in copper pieces: 1 pp = 10 po = 100 pa = 1000 pc.
+const (
+	copperPerPA = 10
+	copperPerPO = 100
+	copperPerPP = 1000
+)
+
+var ErrInsufficientFunds = errors.New("not enough money")
+
+type Purse struct {
+	PP int `json:"pp"`
+	PO int `json:"po"`
+	PA int `json:"pa"`
+	PC int `json:"pc"`
+}
+
+func CharacterPurse(character *database.Character) Purse {
+	return Purse{PP: character.MoneyPP, PO: character.MoneyPO, PA: character.MoneyPA, PC: character.MoneyPC}
+}
+
+// Value is the purse's worth in copper pieces.
+func (p Purse) Value() int {
+	return p.PP*copperPerPP + p.PO*copperPerPO + p.PA*copperPerPA + p.PC
+}
+
+func (p Purse) Add(other Purse) Purse {
+	return Purse{PP: p.PP + other.PP, PO: p.PO + other.PO, PA: p.PA + other.PA, PC: p.PC + other.PC}
+}
+
+func (p Purse) Sub(other Purse) Purse {
+	return Purse{PP: p.PP - other.PP, PO: p.PO - other.PO, PA: p.PA - other.PA, PC: p.PC - other.PC}
+}
+
+// Exchange returns the fewest coins worth value copper pieces.
+func Exchange(value int) Purse {
+	return Purse{
+		PP: value / copperPerPP,
+		PO: value % copperPerPP / copperPerPO,
+		PA: value % copperPerPO / copperPerPA,
+		PC: value % copperPerPA,
+	}
+}
+
+// Pay takes cost copper pieces from the purse. Coins are spent from the
+// largest that fit; when the remainder cannot be paid exactly, the smallest
+// coin covering it is broken and the change is added back in smaller coins.
+func (p Purse) Pay(cost int) (Purse, error) {
+	if cost > p.Value() {
+		return p, ErrInsufficientFunds
+	}
+
+	coins := []*int{&p.PP, &p.PO, &p.PA, &p.PC}
+	values := []int{copperPerPP, copperPerPO, copperPerPA, 1}
+
+	for i, coin := range coins {
+		n := min(*coin, cost/values[i])
+		*coin -= n
+		cost -= n * values[i]
+	}
+
+	if cost == 0 {
+		return p, nil
+	}
+
+	for i := len(coins) - 1; i >= 0; i-- {
+		if *coins[i] > 0 && values[i] >= cost {
+			*coins[i]--
+			change := Exchange(values[i] - cost)
+
+			return p.Add(change), nil
+		}
+	}
+
+	return p, ErrInsufficientFunds
+}
+
+// Item slots. The equipped items of the armor and shield slots make up the
+// character's defense; the weapon slots tell the attack they are used with.
+const (
+	SlotArmor  = "armor"
+	SlotShield = "shield"
+	SlotMelee  = "melee"
+	SlotRanged = "ranged"
+)
+
+var Slots = []string{SlotArmor, SlotShield, SlotMelee, SlotRanged}
+
+// carriedWeightPerStrength is the load in kilograms a character carries
+// without penalty per point of strength.
+const carriedWeightPerStrength = 5
+
+func CarryingCapacity(character *database.Character) float64 {
+	return float64(character.ValueStrength * carriedWeightPerStrength)
+}
+
+func CarriedWeight(items []database.InventoryItem) float64 {
+	total := 0.0
+	for _, item := range items {
+		total += float64(item.Quantity) * item.Weight
+	}
+
+	return total
+}
+
+// ParseEquipment splits free-text equipment into items, one per line or
+// comma-separated entry. A leading number such as "2 torches" or "3x
+// flèches" is read as the quantity.
+func ParseEquipment(text string) []database.InventoryItem {
+	var items []database.InventoryItem
+
+	for _, line := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == ',' || r == ';' }) {
+		name := strings.Trim(strings.TrimSpace(line), "-*• ")
+		if name == "" {
+			continue
+		}
+
+		quantity := 1
+
+		if first, rest, ok := strings.Cut(name, " "); ok {
+			n, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(first), "x"))
+			if err == nil && n > 0 && strings.TrimSpace(rest) != "" {
+				quantity = n
+				name = strings.TrimSpace(rest)
+			}
+		}
+
+		items = append(items, database.InventoryItem{Name: name, Quantity: quantity})
+	}
+
+	return items
+}
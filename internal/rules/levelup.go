@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+)
+
+type HealthMethod string
+
+const (
+	HealthAverage HealthMethod = "average"
+	HealthRoll    HealthMethod = "roll"
+)
+
+var HealthMethods = []HealthMethod{HealthAverage, HealthRoll}
+
+// AverageHitDie is the value taken instead of rolling the hit die, rounded up.
+func AverageHitDie(lifeDice int) int {
+	return lifeDice/2 + 1
+}
+
+// RollHitDie rolls the profile's hit die.
+func RollHitDie(roller *dice.Roller, lifeDice int) (int, error) {
+	result, err := roller.RollString(fmt.Sprintf("1d%d", lifeDice), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Total, nil
+}
+
+// LevelUp is what a character gains on reaching the next level.
+type LevelUp struct {
+	Level      int
+	HitDie     int
+	HealthGain int
+	ManaMax    int
+	ManaGain   int
+	Capability *database.Capability
+	Stats      Stats
+}
+
+// NextLevel computes the next level of the character, gaining hitDie plus
+// the constitution modifier in health, at least one point.
+func NextLevel(character *database.Character, hitDie int, capability *database.Capability) LevelUp {
+	before := Compute(character)
+
+	next := *character
+	next.Level++
+	next.HealthMax += max(1, hitDie+Modifier(character.ValueConstitution))
+
+	after := Compute(&next)
+
+	return LevelUp{
+		Level:      next.Level,
+		HitDie:     hitDie,
+		HealthGain: next.HealthMax - character.HealthMax,
+		ManaMax:    after.ManaMax,
+		ManaGain:   after.ManaMax - before.ManaMax,
+		Capability: capability,
+		Stats:      after,
+	}
+}
+
+// NextCapabilities returns, for each path, the capability following the
+// highest rank the character owns in it.
+func NextCapabilities(owned, candidates []database.Capability) []database.Capability {
+	ranks := map[int]int{}
+	for _, capability := range owned {
+		ranks[capability.PathID] = max(ranks[capability.PathID], capability.Rank)
+	}
+
+	var next []database.Capability
+
+	for _, capability := range candidates {
+		if capability.Rank == ranks[capability.PathID]+1 {
+			next = append(next, capability)
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+)
+
+type RestKind string
+
+const (
+	RestShort RestKind = "short"
+	RestLong  RestKind = "long"
+)
+
+var RestKinds = []RestKind{RestShort, RestLong}
+
+// Rest is what a character recovers by resting. Each recovery die heals the
+// recovery dice of the character, at least one point.
+type Rest struct {
+	Kind           RestKind
+	Rolls          []int
+	HealthGain     int
+	ManaGain       int
+	RecoverySpent  int
+	RecoveryGained int
+}
+
+// PlanRest rolls the rest of the character. A short rest spends up to spend
+// recovery points, stopping once health is full. A long rest also heals one
+// recovery die for free, gives back a recovery point and restores all mana.
+func PlanRest(roller *dice.Roller, character *database.Character, kind RestKind, spend int) (Rest, error) {
+	stats := Compute(character)
+	rest := Rest{Kind: kind}
+
+	missing := max(0, stats.HealthMax-character.HealthRemaining)
+
+	roll := func() error {
+		result, err := roller.RollString(stats.RecoveryDice(), nil)
+		if err != nil {
+			return err
+		}
+
+		heal := max(1, result.Total)
+		rest.Rolls = append(rest.Rolls, heal)
+		rest.HealthGain = min(missing, rest.HealthGain+heal)
+
+		return nil
+	}
+
+	if kind == RestLong {
+		err := roll()
+		if err != nil {
+			return Rest{}, err
+		}
+
+		rest.ManaGain = max(0, stats.ManaMax-character.ManaRemaining)
+	}
+
+	for rest.RecoverySpent < min(spend, character.RecoveryPointsRemaining) && rest.HealthGain < missing {
+		err := roll()
+		if err != nil {
+			return Rest{}, err
+		}
+
+		rest.RecoverySpent++
+	}
+
+	if kind == RestLong && character.RecoveryPointsRemaining-rest.RecoverySpent < stats.RecoveryPointsMax {
+		rest.RecoveryGained = 1
+	}
+
+	return rest, nil
+}
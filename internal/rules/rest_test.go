@@ -0,0 +1,106 @@
+package rules
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+)
+
+func TestPlanRest(t *testing.T) {
+	// A level 1 fighter with a 1d10+0 recovery die and no mana.
+	fighter := func(health, healthMax, recovery int) database.Character {
+		return database.Character{
+			Level: 1, ProfileLifeDice: 10, ProfileMagicalStrength: "NON",
+			ValueStrength: 10, ValueDexterity: 10, ValueConstitution: 10,
+			ValueIntelligence: 10, ValueWisdom: 10, ValueCharisma: 10,
+			HealthMax: healthMax, HealthRemaining: health, RecoveryPointsRemaining: recovery,
+		}
+	}
+
+	// A level 3 wizard with a 1d4+1 recovery die and 9 mana points.
+	wizard := func(health, mana, recovery int) database.Character {
+		return database.Character{
+			Level: 3, ProfileLifeDice: 4, ProfileMagicalStrength: "INT", ProfileManaMaxCompute: 2,
+			ValueStrength: 8, ValueDexterity: 14, ValueConstitution: 10,
+			ValueIntelligence: 17, ValueWisdom: 12, ValueCharisma: 10,
+			HealthMax: 12, HealthRemaining: health, ManaRemaining: mana, RecoveryPointsRemaining: recovery,
+		}
+	}
+
+	weak := fighter(5, 20, 5)
+	weak.ValueConstitution = 6
+
+	tests := []struct {
+		name      string
+		character database.Character
+		kind      RestKind
+		spend     int
+		rolls     []int
+		want      Rest
+	}{
+		{
+			name:      "spend capped by the remaining recovery points",
+			character: fighter(10, 40, 2), kind: RestShort, spend: 5,
+			rolls: []int{3, 6},
+			want:  Rest{Rolls: []int{3, 6}, HealthGain: 9, RecoverySpent: 2},
+		},
+		{
+			name:      "stops once health is full",
+			character: fighter(15, 20, 5), kind: RestShort, spend: 5,
+			rolls: []int{4, 4},
+			want:  Rest{Rolls: []int{4, 4}, HealthGain: 5, RecoverySpent: 2},
+		},
+		{
+			name:      "nothing to heal",
+			character: fighter(20, 20, 5), kind: RestShort, spend: 3,
+			want: Rest{},
+		},
+		{
+			name:      "heals at least 1 per die",
+			character: weak, kind: RestShort, spend: 2,
+			rolls: []int{1, 2},
+			want:  Rest{Rolls: []int{1, 1}, HealthGain: 2, RecoverySpent: 2},
+		},
+		{
+			name:      "long rest restores mana and a recovery point",
+			character: wizard(5, 2, 3), kind: RestLong,
+			rolls: []int{2},
+			want:  Rest{Rolls: []int{3}, HealthGain: 3, ManaGain: 7, RecoveryGained: 1},
+		},
+		{
+			name:      "long rest at the recovery points maximum",
+			character: wizard(5, 9, RecoveryPointsMax), kind: RestLong,
+			rolls: []int{4},
+			want:  Rest{Rolls: []int{5}, HealthGain: 5},
+		},
+		{
+			name:      "long rest spending the point it gives back",
+			character: wizard(2, 0, RecoveryPointsMax), kind: RestLong, spend: 1,
+			rolls: []int{4, 1},
+			want:  Rest{Rolls: []int{5, 2}, HealthGain: 7, ManaGain: 9, RecoverySpent: 1, RecoveryGained: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := dieSource(tt.rolls)
+			tt.want.Kind = tt.kind
+
+			got, err := PlanRest(dice.NewRoller(&source), &tt.character, tt.kind, tt.spend)
+			if err != nil {
+				t.Fatalf("PlanRest() error = %v", err)
+			}
+
+			if len(source) != 0 {
+				t.Errorf("%d dice left unrolled", len(source))
+			}
+
+			if got.Kind != tt.want.Kind || !slices.Equal(got.Rolls, tt.want.Rolls) || got.HealthGain != tt.want.HealthGain ||
+				got.ManaGain != tt.want.ManaGain || got.RecoverySpent != tt.want.RecoverySpent || got.RecoveryGained != tt.want.RecoveryGained {
+				t.Errorf("PlanRest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/dice"
+)
+
+type Modifiers struct {
+	Strength     int `json:"strength"`
+	Dexterity    int `json:"dexterity"`
+	Constitution int `json:"constitution"`
+	Intelligence int `json:"intelligence"`
+	Wisdom       int `json:"wisdom"`
+	Charisma     int `json:"charisma"`
+}
+
+func (m Modifiers) Get(ability Ability) int {
+	switch ability {
+	case Strength:
+		return m.Strength
+	case Dexterity:
+		return m.Dexterity
+	case Constitution:
+		return m.Constitution
+	case Intelligence:
+		return m.Intelligence
+	case Wisdom:
+		return m.Wisdom
+	case Charisma:
+		return m.Charisma
+	}
+
+	return 0
+}
+
+type Stats struct {
+	Modifiers         Modifiers `json:"modifiers"`
+	Defense           int       `json:"defense"`
+	Initiative        int       `json:"initiative"`
+	AttackMelee       int       `json:"attack_melee"`
+	AttackRanged      int       `json:"attack_ranged"`
+	AttackMagic       int       `json:"attack_magic"`
+	HealthMax         int       `json:"health_max"`
+	ManaMax           int       `json:"mana_max"`
+	RecoveryPointsMax int       `json:"recovery_points_max"`
+	LuckPointsMax     int       `json:"luck_points_max"`
+	RecoveryDie       int       `json:"recovery_die"`
+	RecoveryBonus     int       `json:"recovery_bonus"`
+}
+
+// RecoveryDice is the amount of health regained per recovery point, e.g. "1d10+4".
+func (s Stats) RecoveryDice() string {
+	return fmt.Sprintf("1d%d%+d", s.RecoveryDie, s.RecoveryBonus)
+}
+
+func AbilityValue(character *database.Character, ability Ability) int {
+	switch ability {
+	case Strength:
+		return character.ValueStrength
+	case Dexterity:
+		return character.ValueDexterity
+	case Constitution:
+		return character.ValueConstitution
+	case Intelligence:
+		return character.ValueIntelligence
+	case Wisdom:
+		return character.ValueWisdom
+	case Charisma:
+		return character.ValueCharisma
+	}
+
+	return 0
+}
+
+func MagicalStrength(character *database.Character) Ability {
+	ability, ok := magicalStrengths[character.ProfileMagicalStrength]
+	if !ok {
+		return Intelligence
+	}
+
+	return ability
+}
+
+// Compute derives the statistics of the character, active effects included.
+func Compute(character *database.Character) Stats {
+	modifiers := Modifiers{
+		Strength:     Modifier(character.ValueStrength),
+		Dexterity:    Modifier(character.ValueDexterity),
+		Constitution: Modifier(character.ValueConstitution),
+		Intelligence: Modifier(character.ValueIntelligence),
+		Wisdom:       Modifier(character.ValueWisdom),
+		Charisma:     Modifier(character.ValueCharisma),
+	}
+
+	magicModifier := modifiers.Get(MagicalStrength(character))
+
+	manaMax := 0
+	if factor := manaMaxFactors[character.ProfileManaMaxCompute]; factor > 0 {
+		manaMax = max(0, factor*character.Level+magicModifier)
+	}
+
+	stats := Stats{
+		Modifiers:         modifiers,
+		Defense:           baseDefense + character.Armor + character.Shield + modifiers.Dexterity + character.DefenseMisc,
+		Initiative:        character.ValueDexterity + character.InitiativeMisc,
+		AttackMelee:       character.Level + modifiers.Strength,
+		AttackRanged:      character.Level + modifiers.Dexterity,
+		AttackMagic:       character.Level + magicModifier,
+		HealthMax:         character.HealthMax,
+		ManaMax:           manaMax,
+		RecoveryPointsMax: RecoveryPointsMax,
+		LuckPointsMax:     max(0, baseLuckPoints+modifiers.Charisma),
+		RecoveryDie:       character.ProfileLifeDice,
+		RecoveryBonus:     character.Level/2 + modifiers.Constitution,
+	}
+
+	for _, effect := range character.Effects {
+		stats.Defense += effect.Defense
+		stats.Initiative += effect.Initiative
+		stats.AttackMelee += effect.AttackMelee
+		stats.AttackRanged += effect.AttackRanged
+		stats.AttackMagic += effect.AttackMagic
+	}
+
+	return stats
+}
+
+// DiceReferences are the values available to dice expressions: ability
+// modifiers ("@FOR", "@DEX", ...) and the character level ("@NIV").
+func DiceReferences(character *database.Character) map[string]int {
+	refs := map[string]int{
+		"NIV": character.Level,
+	}
+
+	for _, ability := range Abilities {
+		refs[string(ability)] = Modifier(AbilityValue(character, ability))
+	}
+
+	return refs
+}
+
+// ParseDice parses an expression stored to be rolled later, such as the
+// damage of a weapon, checking its references are among DiceReferences.
+func ParseDice(input string) (*dice.Expression, error) {
+	expression, err := dice.Parse(input)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := DiceReferences(&database.Character{})
+
+	for _, term := range expression.Terms {
+		if _, ok := refs[term.Reference]; term.Kind == dice.TermReference && !ok {
+			return nil, fmt.Errorf("%w: unknown reference @%s", dice.ErrInvalidExpression, term.Reference)
+		}
+	}
+
+	return expression, nil
+}
@@ -0,0 +1,110 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+)
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name      string
+		character database.Character
+		want      Stats
+	}{
+		{
+			name: "fighter level 1",
+			character: database.Character{
+				Level: 1, ProfileMagicalStrength: "NON", ProfileManaMaxCompute: 0, ProfileLifeDice: 10, HealthMax: 13,
+				ValueStrength: 16, ValueDexterity: 12, ValueConstitution: 14, ValueIntelligence: 8, ValueWisdom: 10, ValueCharisma: 13,
+				Armor: 4, Shield: 2,
+			},
+			want: Stats{
+				Modifiers: Modifiers{Strength: 3, Dexterity: 1, Constitution: 2, Intelligence: -1, Wisdom: 0, Charisma: 1},
+				Defense:   17, Initiative: 12, AttackMelee: 4, AttackRanged: 2, AttackMagic: 0,
+				HealthMax: 13, ManaMax: 0, RecoveryPointsMax: 5, LuckPointsMax: 3, RecoveryDie: 10, RecoveryBonus: 2,
+			},
+		},
+		{
+			name: "wizard level 3",
+			character: database.Character{
+				Level: 3, ProfileMagicalStrength: "INT", ProfileManaMaxCompute: 2, ProfileLifeDice: 4, HealthMax: 12,
+				ValueStrength: 8, ValueDexterity: 14, ValueConstitution: 10, ValueIntelligence: 17, ValueWisdom: 12, ValueCharisma: 10,
+				DefenseMisc: 1, InitiativeMisc: 2,
+			},
+			want: Stats{
+				Modifiers: Modifiers{Strength: -1, Dexterity: 2, Constitution: 0, Intelligence: 3, Wisdom: 1, Charisma: 0},
+				Defense:   13, Initiative: 16, AttackMelee: 2, AttackRanged: 5, AttackMagic: 6,
+				HealthMax: 12, ManaMax: 9, RecoveryPointsMax: 5, LuckPointsMax: 2, RecoveryDie: 4, RecoveryBonus: 1,
+			},
+		},
+		{
+			name: "priest level 5",
+			character: database.Character{
+				Level: 5, ProfileMagicalStrength: "SAG", ProfileManaMaxCompute: 1, ProfileLifeDice: 8, HealthMax: 30,
+				ValueStrength: 12, ValueDexterity: 9, ValueConstitution: 13, ValueIntelligence: 10, ValueWisdom: 16, ValueCharisma: 6,
+				Armor: 5, Shield: 2,
+			},
+			want: Stats{
+				Modifiers: Modifiers{Strength: 1, Dexterity: -1, Constitution: 1, Intelligence: 0, Wisdom: 3, Charisma: -2},
+				Defense:   16, Initiative: 9, AttackMelee: 6, AttackRanged: 4, AttackMagic: 8,
+				HealthMax: 30, ManaMax: 8, RecoveryPointsMax: 5, LuckPointsMax: 0, RecoveryDie: 8, RecoveryBonus: 3,
+			},
+		},
+		{
+			name: "bard level 2 with negative maxima",
+			character: database.Character{
+				Level: 2, ProfileMagicalStrength: "CHA", ProfileManaMaxCompute: 1, ProfileLifeDice: 6, HealthMax: 8,
+				ValueStrength: 10, ValueDexterity: 10, ValueConstitution: 3, ValueIntelligence: 10, ValueWisdom: 10, ValueCharisma: 3,
+			},
+			want: Stats{
+				Modifiers: Modifiers{Strength: 0, Dexterity: 0, Constitution: -4, Intelligence: 0, Wisdom: 0, Charisma: -4},
+				Defense:   10, Initiative: 10, AttackMelee: 2, AttackRanged: 2, AttackMagic: -2,
+				HealthMax: 8, ManaMax: 0, RecoveryPointsMax: 5, LuckPointsMax: 0, RecoveryDie: 6, RecoveryBonus: -3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(&tt.character)
+			if got != tt.want {
+				t.Errorf("Compute() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeEffects(t *testing.T) {
+	character := &database.Character{
+		Level: 1, ValueStrength: 16, ValueDexterity: 12, ValueConstitution: 10, ValueIntelligence: 10, ValueWisdom: 10, ValueCharisma: 10,
+		Armor: 4, Shield: 2,
+		Effects: []database.Effect{
+			{Defense: 2, AttackMelee: -1},
+			{Initiative: 3, AttackRanged: 1, AttackMagic: 2},
+		},
+	}
+
+	got := Compute(character)
+
+	if got.Defense != 19 || got.Initiative != 15 || got.AttackMelee != 3 || got.AttackRanged != 3 || got.AttackMagic != 3 {
+		t.Errorf("Compute() = %+v, want defense 19, initiative 15 and attacks 3, 3, 3", got)
+	}
+}
+
+func TestRecoveryDice(t *testing.T) {
+	tests := []struct {
+		stats Stats
+		want  string
+	}{
+		{Stats{RecoveryDie: 10, RecoveryBonus: 2}, "1d10+2"},
+		{Stats{RecoveryDie: 6, RecoveryBonus: -3}, "1d6-3"},
+		{Stats{RecoveryDie: 4, RecoveryBonus: 0}, "1d4+0"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.stats.RecoveryDice(); got != tt.want {
+			t.Errorf("RecoveryDice() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package rules
+
+// Rule tables from Chroniques Oubliées Fantasy, matching the Django
+// implementation of the character sheet.
+
+type Ability string
+
+const (
+	Strength     Ability = "FOR"
+	Dexterity    Ability = "DEX"
+	Constitution Ability = "CON"
+	Intelligence Ability = "INT"
+	Wisdom       Ability = "SAG"
+	Charisma     Ability = "CHA"
+)
+
+var Abilities = []Ability{Strength, Dexterity, Constitution, Intelligence, Wisdom, Charisma}
+
+const (
+	baseDefense       = 10
+	baseLuckPoints    = 2
+	RecoveryPointsMax = 5
+
+	minModifier      = -4
+	minModifierValue = 4
+)
+
+// magicalStrengths maps Profile.MagicalStrength to the ability used for magic
+// attacks and mana. Profiles without magic ("NON") fall back to intelligence
+// for magic attacks.
+var magicalStrengths = map[string]Ability{
+	"NON": Intelligence,
+	"INT": Intelligence,
+	"SAG": Wisdom,
+	"CHA": Charisma,
+}
+
+// manaMaxFactors maps Profile.ManaMaxCompute to the level multiplier used for
+// the mana pool: no mana, one point per level or two points per level.
+var manaMaxFactors = map[int]int{
+	0: 0,
+	1: 1,
+	2: 2,
+}
+
+// Modifier returns the ability modifier for an ability value: -4 up to 3,
+// then one point every two values around 10.
+func Modifier(value int) int {
+	if value < minModifierValue {
+		return minModifier
+	}
+
+	diff := value - 10
+	if diff < 0 {
+		return (diff - 1) / 2
+	}
+
+	return diff / 2
+}
@@ -0,0 +1,59 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+)
+
+func TestModifier(t *testing.T) {
+	tests := []struct {
+		value int
+		want  int
+	}{
+		{1, -4},
+		{3, -4},
+		{4, -3},
+		{5, -3},
+		{6, -2},
+		{7, -2},
+		{8, -1},
+		{9, -1},
+		{10, 0},
+		{11, 0},
+		{12, 1},
+		{13, 1},
+		{14, 2},
+		{16, 3},
+		{18, 4},
+		{20, 5},
+		{21, 5},
+	}
+
+	for _, tt := range tests {
+		if got := Modifier(tt.value); got != tt.want {
+			t.Errorf("Modifier(%d) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMagicalStrength(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    Ability
+	}{
+		{"NON", Intelligence},
+		{"INT", Intelligence},
+		{"SAG", Wisdom},
+		{"CHA", Charisma},
+		{"", Intelligence},
+		{"FOR", Intelligence},
+	}
+
+	for _, tt := range tests {
+		character := &database.Character{ProfileMagicalStrength: tt.profile}
+		if got := MagicalStrength(character); got != tt.want {
+			t.Errorf("MagicalStrength(%q) = %s, want %s", tt.profile, got, tt.want)
+		}
+	}
+}
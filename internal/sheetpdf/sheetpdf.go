@@ -0,0 +1,351 @@
+// Package sheetpdf lays the character sheet out as a PDF for printing. It
+// only uses the core PDF fonts, so that no font file has to be shipped.
+package sheetpdf
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"github.com/Crocmagnon/charasheet-go/internal/rules"
+	"github.com/go-pdf/fpdf"
+)
+
+type PaperSize string
+
+const (
+	A4     PaperSize = "A4"
+	Letter PaperSize = "Letter"
+)
+
+var PaperSizes = []PaperSize{A4, Letter}
+
+const (
+	font       = "Helvetica"
+	margin     = 15.0
+	lineHeight = 5.0
+	labelWidth = 50.0
+)
+
+type Capability struct {
+	database.Capability
+	Description template.HTML
+}
+
+// Item is an inventory item, Nested when it is listed under its container.
+type Item struct {
+	database.InventoryItem
+	Nested bool
+}
+
+type Sheet struct {
+	Character    *database.Character
+	Capabilities []Capability
+	Inventory    []Item
+	Notes        template.HTML
+}
+
+type writer struct {
+	pdf *fpdf.Fpdf
+	tr  func(string) string
+}
+
+// Write lays the sheet out on pages of the given size.
+func Write(w io.Writer, sheet *Sheet, size PaperSize) error {
+	return layout(sheet, size).Output(w)
+}
+
+func layout(sheet *Sheet, size PaperSize) *fpdf.Fpdf {
+	pdf := fpdf.New("P", "mm", string(size), "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.SetTitle(sheet.Character.Name, true)
+	pdf.SetCreator("charasheet", true)
+	pdf.AliasNbPages("")
+
+	sw := &writer{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-margin + 5)
+		pdf.SetFont(font, "I", 8)
+		pdf.CellFormat(0, 4, sw.tr(fmt.Sprintf("%s – page %d/{nb}", sheet.Character.Name, pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+
+	sw.identity(sheet.Character)
+	sw.abilities(sheet.Character)
+	sw.combat(sheet.Character)
+	sw.capabilities(sheet.Capabilities)
+	sw.inventory(sheet.Character, sheet.Inventory)
+	sw.notes(sheet.Notes)
+
+	return pdf
+}
+
+func (sw *writer) heading(title string) {
+	sw.pdf.Ln(3)
+	sw.pdf.SetFont(font, "B", 13)
+	sw.pdf.CellFormat(0, 7, sw.tr(title), "B", 1, "L", false, 0, "")
+	sw.pdf.Ln(1)
+	sw.pdf.SetFont(font, "", 10)
+}
+
+func (sw *writer) row(label, value string) {
+	sw.pdf.SetFont(font, "B", 10)
+	sw.pdf.CellFormat(labelWidth, lineHeight, sw.tr(label), "", 0, "L", false, 0, "")
+	sw.pdf.SetFont(font, "", 10)
+	sw.pdf.MultiCell(0, lineHeight, sw.tr(value), "", "L", false)
+}
+
+func (sw *writer) identity(c *database.Character) {
+	sw.pdf.SetFont(font, "B", 20)
+	sw.pdf.CellFormat(0, 10, sw.tr(c.Name), "", 1, "L", false, 0, "")
+
+	sw.pdf.SetFont(font, "", 11)
+	sw.pdf.CellFormat(0, 6, sw.tr(fmt.Sprintf("%s · %s · niveau %d · joueur : %s", c.RaceName, c.ProfileName, c.Level, c.PlayerName)), "", 1, "L", false, 0, "")
+	sw.pdf.CellFormat(0, 6, sw.tr(fmt.Sprintf("Genre %s · %d ans · %d cm · %d kg", c.Gender, c.Age, c.Height, c.Weight)), "", 1, "L", false, 0, "")
+}
+
+func (sw *writer) abilities(c *database.Character) {
+	sw.heading("Caractéristiques")
+
+	abilities := []struct {
+		name  string
+		value int
+	}{
+		{"Force", c.ValueStrength},
+		{"Dextérité", c.ValueDexterity},
+		{"Constitution", c.ValueConstitution},
+		{"Intelligence", c.ValueIntelligence},
+		{"Sagesse", c.ValueWisdom},
+		{"Charisme", c.ValueCharisma},
+	}
+
+	width, _ := sw.pdf.GetPageSize()
+	cell := (width - 2*margin) / float64(len(abilities))
+
+	sw.pdf.SetFont(font, "B", 9)
+	for _, ability := range abilities {
+		sw.pdf.CellFormat(cell, 6, sw.tr(ability.name), "1", 0, "C", false, 0, "")
+	}
+	sw.pdf.Ln(-1)
+
+	sw.pdf.SetFont(font, "", 12)
+	for _, ability := range abilities {
+		sw.pdf.CellFormat(cell, 8, fmt.Sprintf("%d (%s)", ability.value, signed(rules.Modifier(ability.value))), "1", 0, "C", false, 0, "")
+	}
+	sw.pdf.Ln(-1)
+}
+
+func (sw *writer) combat(c *database.Character) {
+	stats := rules.Compute(c)
+
+	sw.heading("Combat")
+	sw.row("Défense", fmt.Sprintf("%d (armure %d, bouclier %d, divers %s)", stats.Defense, c.Armor, c.Shield, signed(c.DefenseMisc)))
+	sw.row("Initiative", strconv.Itoa(stats.Initiative))
+	sw.row("Attaque au contact", signed(stats.AttackMelee))
+	sw.row("Attaque à distance", signed(stats.AttackRanged))
+	sw.row("Attaque magique", signed(stats.AttackMagic))
+	sw.row("Récupération", stats.RecoveryDice())
+	sw.row("Réduction des dégâts", c.DamageReduction)
+
+	sw.heading("Compteurs")
+	sw.row("Points de vie", fmt.Sprintf("%d / %d", c.HealthRemaining, stats.HealthMax))
+	sw.row("Points de mana", fmt.Sprintf("%d / %d", c.ManaRemaining, stats.ManaMax))
+	sw.row("Points de récupération", fmt.Sprintf("%d / %d", c.RecoveryPointsRemaining, stats.RecoveryPointsMax))
+	sw.row("Points de chance", fmt.Sprintf("%d / %d", c.LuckPointsRemaining, stats.LuckPointsMax))
+}
+
+func (sw *writer) capabilities(capabilities []Capability) {
+	sw.heading("Capacités")
+
+	if len(capabilities) == 0 {
+		sw.pdf.MultiCell(0, lineHeight, sw.tr("Aucune capacité."), "", "L", false)
+		return
+	}
+
+	for _, capability := range capabilities {
+		title := fmt.Sprintf("%s (%s, rang %d", capability.Name, capability.PathName, capability.Rank)
+		if capability.Limited {
+			title += ", L"
+		}
+		if capability.Spell {
+			title += ", sort"
+		}
+
+		sw.pdf.SetFont(font, "B", 10)
+		sw.pdf.MultiCell(0, lineHeight, sw.tr(title+")"), "", "L", false)
+		sw.pdf.SetFont(font, "", 10)
+		sw.html(capability.Description)
+		sw.pdf.Ln(2)
+	}
+}
+
+func (sw *writer) inventory(c *database.Character, items []Item) {
+	sw.heading("Équipement")
+
+	sw.row("Bourse", fmt.Sprintf("%d pp, %d po, %d pa, %d pc", c.MoneyPP, c.MoneyPO, c.MoneyPA, c.MoneyPC))
+
+	if len(items) == 0 {
+		sw.row("Objets", c.Equipment)
+		return
+	}
+
+	for _, item := range items {
+		line := item.Name
+		if item.Quantity > 1 {
+			line = fmt.Sprintf("%d × %s", item.Quantity, item.Name)
+		}
+		if item.Weight > 0 {
+			line += fmt.Sprintf(" (%s kg)", strconv.FormatFloat(item.Weight*float64(item.Quantity), 'f', -1, 64))
+		}
+		if item.Equipped {
+			line += ", équipé"
+		}
+		if item.Defense > 0 {
+			line += fmt.Sprintf(", DEF +%d", item.Defense)
+		}
+		if item.Damage != "" {
+			line += ", DM " + item.Damage
+		}
+
+		indent := 0.0
+		if item.Nested {
+			indent = 6
+		}
+
+		sw.pdf.SetX(margin + indent)
+		sw.pdf.MultiCell(0, lineHeight, sw.tr("• "+line), "", "L", false)
+	}
+}
+
+func (sw *writer) notes(notes template.HTML) {
+	if strings.TrimSpace(string(notes)) == "" {
+		return
+	}
+
+	sw.heading("Notes")
+	sw.html(notes)
+}
+
+var (
+	tagRx   = regexp.MustCompile(`<(/?)([a-zA-Z0-9]+)[^>]*>`)
+	spaceRx = regexp.MustCompile(`\s+`)
+)
+
+// html writes sanitized HTML as flowing text, keeping emphasis, headings,
+// paragraphs and list items. Other elements only contribute their text.
+func (sw *writer) html(s template.HTML) {
+	var bold, italic int
+
+	setStyle := func() {
+		style := ""
+		if bold > 0 {
+			style += "B"
+		}
+		if italic > 0 {
+			style += "I"
+		}
+		sw.pdf.SetFont(font, style, 10)
+	}
+
+	text := func(t string) {
+		t = spaceRx.ReplaceAllString(html.UnescapeString(t), " ")
+		if strings.TrimSpace(t) == "" && sw.pdf.GetX() <= margin+0.01 {
+			return
+		}
+
+		if sw.pdf.GetX() <= margin+0.01 {
+			t = strings.TrimLeft(t, " ")
+		}
+
+		sw.pdf.Write(lineHeight, sw.tr(t))
+	}
+
+	newLine := func() {
+		if sw.pdf.GetX() > margin+0.01 {
+			sw.pdf.Ln(lineHeight)
+		}
+	}
+
+	content := string(s)
+	pos := 0
+
+	for _, m := range tagRx.FindAllStringSubmatchIndex(content, -1) {
+		text(content[pos:m[0]])
+		pos = m[1]
+
+		closing := m[3] > m[2]
+		name := strings.ToLower(content[m[4]:m[5]])
+
+		switch name {
+		case "strong", "b", "th":
+			if closing {
+				bold--
+			} else {
+				bold++
+			}
+
+			setStyle()
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			newLine()
+
+			if closing {
+				bold--
+				sw.pdf.Ln(lineHeight)
+			} else {
+				bold++
+			}
+
+			setStyle()
+		case "em", "i":
+			if closing {
+				italic--
+			} else {
+				italic++
+			}
+
+			setStyle()
+		case "p", "blockquote", "pre", "tr", "ul", "ol":
+			if closing {
+				newLine()
+				sw.pdf.Ln(1)
+			} else {
+				newLine()
+			}
+		case "li":
+			if closing {
+				newLine()
+			} else {
+				newLine()
+				sw.pdf.Write(lineHeight, sw.tr("• "))
+			}
+		case "td":
+			if closing {
+				sw.pdf.Write(lineHeight, "   ")
+			}
+		case "br", "hr":
+			sw.pdf.Ln(lineHeight)
+		}
+	}
+
+	text(content[pos:])
+	newLine()
+
+	bold, italic = 0, 0
+	setStyle()
+}
+
+func signed(n int) string {
+	if n >= 0 {
+		return "+" + strconv.Itoa(n)
+	}
+
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,167 @@
+package sheetpdf
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Crocmagnon/charasheet-go/internal/database"
+	"golang.org/x/text/encoding/charmap"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestWrite compares the text shown on each page with the golden files, so
+// that changes to the PDF encoding alone do not fail it.
+func TestWrite(t *testing.T) {
+	container := 1
+	sheet := &Sheet{
+		Character: &database.Character{
+			Name: "Aldric", PlayerName: "p1", RaceName: "Humain", ProfileName: "Guerrier",
+			ProfileLifeDice: 10, ProfileMagicalStrength: "NON", Level: 3,
+			Gender: "M", Age: 27, Height: 182, Weight: 80,
+			ValueStrength: 16, ValueDexterity: 12, ValueConstitution: 14,
+			ValueIntelligence: 8, ValueWisdom: 10, ValueCharisma: 13,
+			HealthMax: 28, HealthRemaining: 21, Armor: 4, Shield: 2, DefenseMisc: -1,
+			RecoveryPointsRemaining: 4, LuckPointsRemaining: 3,
+			MoneyPO: 12, MoneyPA: 5, DamageReduction: "2 contre les armes contondantes",
+		},
+		Capabilities: []Capability{
+			{
+				Capability:  database.Capability{Name: "Vigueur", PathName: "Voie du soldat", Rank: 1},
+				Description: "<p>Le personnage gagne <strong>+2</strong> aux tests de <em>FOR</em>.</p>",
+			},
+			{
+				Capability:  database.Capability{Name: "Charge", PathName: "Voie du soldat", Rank: 2, Limited: true},
+				Description: "<ul><li>Se déplace de 20 m</li><li>Attaque au contact</li></ul>",
+			},
+		},
+		Inventory: []Item{
+			{InventoryItem: database.InventoryItem{ID: container, Name: "Sac à dos", Quantity: 1, Weight: 1.5, IsContainer: true}},
+			{InventoryItem: database.InventoryItem{Name: "Torche", Quantity: 3, Weight: 0.5, ContainerID: &container}, Nested: true},
+			{InventoryItem: database.InventoryItem{Name: "Épée longue", Quantity: 1, Weight: 2, Equipped: true, Slot: "melee", Damage: "1d8+@FOR"}},
+			{InventoryItem: database.InventoryItem{Name: "Cotte de mailles", Quantity: 1, Weight: 15, Equipped: true, Slot: "armor", Defense: 4}},
+		},
+		Notes: "<h2>Journal</h2><p>Dette envers <b>Nimue</b> : 3 po.</p>",
+	}
+
+	for _, size := range PaperSizes {
+		t.Run(string(size), func(t *testing.T) {
+			pdf := layout(sheet, size)
+			pdf.SetCompression(false)
+
+			var buf bytes.Buffer
+			if err := pdf.Output(&buf); err != nil {
+				t.Fatalf("Output() error = %v", err)
+			}
+
+			got, err := pageTexts(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "sheet-"+string(size)+".txt")
+
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != string(want) {
+				t.Errorf("the text of the sheet differs from %s, run the test with -update if the change is expected:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// pageTexts returns the strings shown by the Tj operators of an
+// uncompressed PDF, one per line, under a header for each page content
+// stream.
+func pageTexts(pdf []byte) (string, error) {
+	var sb strings.Builder
+
+	page := 0
+
+	for {
+		start := bytes.Index(pdf, []byte("stream\n"))
+		if start < 0 {
+			break
+		}
+
+		pdf = pdf[start+len("stream\n"):]
+
+		end := bytes.Index(pdf, []byte("endstream"))
+		if end < 0 {
+			return "", fmt.Errorf("unterminated stream")
+		}
+
+		page++
+		fmt.Fprintf(&sb, "--- page %d ---\n", page)
+
+		content := pdf[:end]
+		pdf = pdf[end+len("endstream"):]
+
+		for {
+			text, rest, ok := nextString(content)
+			if !ok {
+				break
+			}
+
+			content = rest
+
+			if !bytes.HasPrefix(bytes.TrimLeft(content, " "), []byte("Tj")) {
+				continue
+			}
+
+			decoded, err := charmap.Windows1252.NewDecoder().Bytes(text)
+			if err != nil {
+				return "", err
+			}
+
+			sb.Write(decoded)
+			sb.WriteByte('\n')
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// nextString returns the first literal string of content, unescaped, and
+// what follows it.
+func nextString(content []byte) (text, rest []byte, ok bool) {
+	start := bytes.IndexByte(content, '(')
+	if start < 0 {
+		return nil, nil, false
+	}
+
+	escapes := map[byte]byte{'n': '\n', 'r': '\r', 't': '\t'}
+
+	for i := start + 1; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '\\' && i+1 < len(content):
+			i++
+
+			if escaped, ok := escapes[content[i]]; ok {
+				text = append(text, escaped)
+			} else {
+				text = append(text, content[i])
+			}
+		case c == ')':
+			return text, content[i+1:], true
+		default:
+			text = append(text, c)
+		}
+	}
+
+	return nil, nil, false
+}